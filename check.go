@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// checkWords decides whether each of the queries is compound, given the
+// dictionary d.  The queries needn't be in the dictionary themselves.
+// The results come back in the same order as the queries.
+func checkWords(queries words, d dictionary) (results potentials, allCompound bool) {
+	allCompound = true
+	for _, q := range queries {
+		p := potential{whole: q, prefixes: prefixesOf(q, d.graph)}
+		if !(&p).isCompound(d) {
+			allCompound = false
+		}
+		results = append(results, p)
+	}
+	return
+}
+
+// readQueries reads words to check from r, one per line.  Unlike a word
+// list, each line is taken as it is, tabs and all, and a word which turns
+// up twice is checked twice, so that there's an answer for every line, in
+// the same order.  Only blank lines, which have no word to check, and
+// lines longer than lim allows are treated as they would be in a word
+// list.
+func readQueries(r io.Reader, lim lineLimit) (queries words, _ error) {
+	in := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, size, err := readLine(in, lim.max)
+		if err == io.EOF {
+			return queries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: %v", displayName("-"), lineNo, err)
+		}
+		if lim.max > 0 && size > lim.max {
+			switch lim.policy {
+			case oversizeSkip:
+				continue
+			case oversizeFail:
+				return nil, fmt.Errorf("%s: line %d: entry is %d bytes long, over the limit of %d",
+					displayName("-"), lineNo, size, lim.max)
+			}
+		}
+		if len(line) == 0 {
+			continue
+		}
+		queries = append(queries, line)
+	}
+}
+
+// checkFlags are the flags of the check command, which loads the
+// dictionary once, then reports whether each word it's asked about is
+// compound.
+func checkFlags(fs *flag.FlagSet) action {
+	var dictFiles fileList
+	fs.Var(&dictFiles, "d", "word `file` to check words against")
+	var df dictionaryFlags
+	df.register(fs)
+	var fuzz fuzziness
+	var distance string
+	fuzz.register(fs, &distance)
+	var outOpts outputOptions
+	outOpts.register(fs)
+
+	return func(fs *flag.FlagSet) int {
+		if len(dictFiles) == 0 {
+			return misuse(fs, errors.New("no dictionary given with -d"))
+		}
+		if err := df.finish(); err != nil {
+			return misuse(fs, err)
+		}
+		if err := fuzz.finish(distance); err != nil {
+			return misuse(fs, err)
+		}
+		format, err := outOpts.formatterFor(os.Stdout)
+		if err != nil {
+			return misuse(fs, fmt.Errorf("bad output settings: %v", err))
+		}
+
+		dict, ws, err := loadDictionary(dictFiles, df)
+		if err != nil {
+			return cantLoad(fs, err)
+		}
+		dict.fuzz = fuzz
+
+		var queries words
+		if fs.NArg() == 0 || (fs.NArg() == 1 && fs.Arg(0) == "-") {
+			if queries, err = readQueries(os.Stdin, df.load.lineLimit); err != nil {
+				complain(err)
+				return exitIO
+			}
+		} else {
+			for _, q := range fs.Args() {
+				queries = append(queries, word(q))
+			}
+		}
+
+		results, allCompound := checkWords(queries, dict)
+		for i := range results {
+			results[i].sources = ws.sources(results[i].whole)
+		}
+		if err = format(os.Stdout, results); err != nil {
+			complain(err)
+			return exitIO
+		}
+		if !allCompound {
+			return exitNoCompound
+		}
+		return exitSuccess
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckWords(t *testing.T) {
+	// Neither of the first two is on the list, and foobar is.
+	queries := words{word("quartsplat"), word("fibble"), word("foobar")}
+	results, allCompound := checkWords(queries, testDict)
+	if allCompound || len(results) != len(queries) {
+		t.Fatalf("checkWords - Got %v (all compound: %v)", results, allCompound)
+	}
+	expected := []string{
+		"quartsplat = quart + splat",
+		"fibble [NOT COMPOUND]",
+		"foobar = foo + bar",
+	}
+	for i, r := range results {
+		if r.String() != expected[i] {
+			t.Errorf("checkWords - Expected %q, got %q", expected[i], r.String())
+		}
+	}
+
+	_, allCompound = checkWords(queries[:1], testDict)
+	if !allCompound {
+		t.Errorf("checkWords - %s should be all compound", queries[0])
+	}
+}
+
+func TestReadQueries(t *testing.T) {
+	queries, err := readQueries(strings.NewReader("foobar\nfibble\n"), noLimit)
+	if err != nil || !reflect.DeepEqual(queries, words{word("foobar"), word("fibble")}) {
+		t.Errorf("readQueries - Got %q (error %v)", queries, err)
+	}
+	queries, err = readQueries(strings.NewReader("foo\tlots\nbar\n\nfoo\tlots\n"), noLimit)
+	expected := words{word("foo\tlots"), word("bar"), word("foo\tlots")}
+	if err != nil || !reflect.DeepEqual(queries, expected) {
+		t.Errorf("readQueries - Expected every line as it is, got %q (error %v)", queries, err)
+	}
+	lim := lineLimit{max: 3, policy: oversizeFail}
+	if _, err = readQueries(strings.NewReader("foobar\n"), lim); err == nil ||
+		!strings.Contains(err.Error(), "<stdin>") {
+		t.Errorf("readQueries - Expected an error naming <stdin>, got %v", err)
+	}
+}
+
+func TestDictionaryFlagsFinish(t *testing.T) {
+	df := dictionaryFlags{load: loadOptions{lineLimit: noLimit}, verbose: true}
+	if err := df.finish(); err != nil || df.load.progress != os.Stderr {
+		t.Errorf("dictionaryFlags.finish - Got %+v (error %v)", df, err)
+	}
+	df = dictionaryFlags{load: loadOptions{lineLimit: lineLimit{policy: "ignore"}}}
+	if err := df.finish(); err == nil {
+		t.Errorf("dictionaryFlags.finish - Expected an error for %+v", df.load.lineLimit)
+	}
+}
+
+func TestLoadDictionary(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "word.list")
+	stop := filepath.Join(dir, "stop.list")
+	if err := os.WriteFile(list, []byte("foo\nbar\nquux\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stop, []byte("bar\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	df := dictionaryFlags{stop: stop, load: loadOptions{lineLimit: noLimit}}
+	d, ws, err := loadDictionary([]string{list}, df)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ws.list) != 3 || d.minLen != 3 || !isWord(word("quux"), d.graph) {
+		t.Errorf("loadDictionary - Got %q, minLen %d", ws.list, d.minLen)
+	}
+	if d.isComponent(word("bar")) || !d.isComponent(word("foo")) {
+		t.Errorf("loadDictionary - The stop list wasn't applied")
+	}
+
+	if _, _, err = loadDictionary([]string{filepath.Join(dir, "missing.list")}, df); err == nil {
+		t.Errorf("loadDictionary - Expected an error for a missing list")
+	}
+}
@@ -0,0 +1,606 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// An action is what a command does once its flags have been parsed.  It
+// returns the status to exit with.
+type action func(fs *flag.FlagSet) int
+
+// An operand is something a command takes other than a flag.
+type operand struct {
+	name string
+	help string
+}
+
+// A command is one of the things compound can do, named by the first
+// argument it's given.  flags registers the command's flags on a flag
+// set, and returns what to do once they've been parsed.  usage() works
+// from the same flag set, so a command's usage can't fall out of step
+// with what it actually accepts.
+type command struct {
+	name     string
+	operands string
+	summary  string
+	about    string
+	takes    []operand
+	flags    func(fs *flag.FlagSet) action
+}
+
+// commands are listed in the order usage() describes them.  help is
+// added by init(), since it needs to read the list.
+var commands = []*command{
+	{
+		name:     "longest",
+		operands: "< - | filename [filename ...] >",
+		summary:  "Finds the longest compound word in the word lists.",
+		about: "Finds the longest word which is entirely composed of other words from the " +
+			"lists, or with -n, the n longest.  This is also what happens when no command is " +
+			"given at all.",
+		takes: []operand{stdinOperand, filenameOperand},
+		flags: longestFlags,
+	},
+	{
+		name:     "all",
+		operands: "< - | filename [filename ...] >",
+		summary:  "Lists every compound word in the word lists, longest first.",
+		about:    "Lists every word which is entirely composed of other words from the lists, longest first.",
+		takes:    []operand{stdinOperand, filenameOperand},
+		flags:    allFlags,
+	},
+	{
+		name:     "split",
+		operands: "< - | filename [filename ...] >",
+		summary:  "Splits every word in the word lists, compound or not.",
+		about: "Reports on every word in the lists, or with -candidates, every word on those, in " +
+			"the order they were read, with its components if it's compound and as [NOT " +
+			"COMPOUND] if it isn't.  This is most useful with -candidates and -components, to " +
+			"split one list with another.",
+		takes: []operand{stdinOperand, filenameOperand},
+		flags: splitFlags,
+	},
+	{
+		name:     "check",
+		operands: "[ - | word [word ...] ]",
+		summary:  "Checks whether particular words are compound, given the word lists.",
+		about: "Reports whether each of the given words is compound, given the words in the " +
+			"lists named by -d, whether or not it's on them itself.  Exits with 0 only if every " +
+			"word was compound.",
+		takes: []operand{
+			{"-", "Indicates that the words to check should be read from STDIN, one per line.  " +
+				"This is also what happens if no words are given."},
+			{"word", "A word to check."},
+		},
+		flags: checkFlags,
+	},
+	{
+		name:     "stats",
+		operands: "< - | filename [filename ...] >",
+		summary:  "Reports facts about the word lists and their graph.",
+		about: "Checks every word, and reports facts about the lists and their graph: counts of " +
+			"words, candidates and compound words by length, compound words by number of " +
+			"components, the size and shape of the graph, and how long each phase of the run took.",
+		takes: []operand{stdinOperand, filenameOperand},
+		flags: statsFlags,
+	},
+	{
+		name:     "explain",
+		operands: "word < - | filename [filename ...] >",
+		summary:  "Shows why a word is or isn't compound.",
+		about: "Shows why the given word is or isn't compound: the prefixes found for it, every " +
+			"split that was tried and which pieces weren't words, and how much of it could be " +
+			"covered if not all of it.",
+		takes: []operand{{"word", "The word to explain.  It need not be on the lists."}, stdinOperand, filenameOperand},
+		flags: explainFlags,
+	},
+	{
+		name:     "dot",
+		operands: "word < - | filename [filename ...] >",
+		summary:  "Draws the ways a word can be split as a Graphviz DOT graph.",
+		about: "Draws every way the given word can be split as a Graphviz DOT graph, with the " +
+			"split that was chosen highlighted.  Render it with \"dot -Tsvg\".",
+		takes: []operand{{"word", "The word to draw.  It need not be on the lists."}, stdinOperand, filenameOperand},
+		flags: dotFlags,
+	},
+	{
+		name:     "generate",
+		operands: "< - | filename [filename ...] >",
+		summary:  "Proposes new compound words, made of words from the lists.",
+		about: "Proposes names made by joining two or three words from the lists at random, " +
+			"which aren't on the lists themselves, are the right length, match -pattern if it's " +
+			"given, and contain no word from -avoid, even across a join.  The same -seed always " +
+			"proposes the same names.  How many were proposed, and how many combinations there " +
+			"are of the right length, is reported on STDERR.",
+		takes: []operand{stdinOperand, filenameOperand},
+		flags: generateFlags,
+	},
+	{
+		name:     "rack",
+		operands: "tiles < - | filename [filename ...] >",
+		summary:  "Lists the words which can be made from a rack of letters.",
+		about: "Lists every word on the lists which can be made from the tiles on the rack, " +
+			"using each no more often than it's there, and every run of up to -parts words which " +
+			"can be, longest first.  Each is reported the way check reports it, so a word from the lists " +
+			"shows as compound only if it's made of other words from them.",
+		takes: []operand{
+			{"tiles", "The letters on the rack, such as aeinrst.  A ? is a blank, which can be " +
+				"played as any letter."},
+			stdinOperand, filenameOperand,
+		},
+		flags: rackFlags,
+	},
+	{
+		name:    "diff",
+		summary: "Compares the compound words of two sets of word lists.",
+		about: "Compares the compound words of the lists named by -old with those of the lists " +
+			"named by -new, and reports the ones which appeared (+), disappeared (-) or are now " +
+			"split differently (~), longest first, then the longest compound word of each, and " +
+			"the number of compound words of each length where it changed.",
+		flags: diffFlags,
+	},
+	{
+		name:     "complete",
+		operands: "text < - | filename [filename ...] >",
+		summary:  "Lists the words which begin with the given text.",
+		about: "Lists the words on the lists which begin with the given text, in order, or with " +
+			"-order frequency, the most frequent first.  With -compound, the text is taken to be " +
+			"a compound word in the middle of being typed: as much of it as is made of whole " +
+			"words is kept, and what's left is completed as the next component.",
+		takes: []operand{{"text", "The beginning of a word."}, stdinOperand, filenameOperand},
+		flags: completeFlags,
+	},
+	{
+		name:     "coprocess",
+		operands: "filename [filename ...]",
+		summary:  "Answers JSON requests on STDIN, one per line.",
+		about: "Reads requests from STDIN, one JSON object per line, such as " +
+			"{\"id\": 1, \"op\": \"decompose\", \"word\": \"backyard\"}, and answers each with a " +
+			"line of JSON on STDOUT as soon as it's read, such as {\"id\": 1, \"result\": ...}.  " +
+			"The op may be decompose, check or prefix.",
+		takes: []operand{filenameOperand},
+		flags: coprocessFlags,
+	},
+	{
+		name:    "shell",
+		summary: "Loads the word lists once, then takes commands to question and change them.",
+		about: "Loads the lists named by -d once, then takes commands from STDIN, one per line, " +
+			"until it runs out of them.  \"help\" lists the commands.  -format applies to the " +
+			"split command.",
+		flags: shellFlags,
+	},
+	{
+		name:    "serve",
+		summary: "Answers questions about the word lists over HTTP.",
+		about: "Loads the lists named by -d once, then answers requests with JSON records like " +
+			"those of -format json: POST /decompose with {\"words\": [\"backyard\", ...]} gets a " +
+			"record for each word, GET /check?word=w the record for w, and GET /longest?n=10 the " +
+			"n longest compound words, longest first.  It stops gracefully on SIGTERM or an interrupt.",
+		flags: serveFlags,
+	},
+}
+
+func init() {
+	commands = append(commands, &command{
+		name:     "help",
+		operands: "[command]",
+		summary:  "Describes a command, or lists them all.",
+		flags: func(fs *flag.FlagSet) action {
+			return func(fs *flag.FlagSet) int {
+				var c *command
+				if fs.NArg() > 0 {
+					if c = findCommand(fs.Arg(0)); c == nil {
+						fmt.Fprintf(os.Stderr, "There's no %q command.\n", fs.Arg(0))
+						fmt.Fprint(os.Stderr, usage(nil))
+						return exitUsage
+					}
+				}
+				fmt.Fprint(os.Stdout, usage(c))
+				return exitSuccess
+			}
+		},
+	})
+}
+
+var stdinOperand = operand{"-", "Indicates that words should be read from STDIN."}
+
+var filenameOperand = operand{"filename", "Specifies a file containing a list of words to read in.  " +
+	"Specifying multiple files will read them all in and work on the aggregate list.  " +
+	"A directory is walked recursively, and every file under it is read.  A quoted glob " +
+	"pattern, such as 'lists/*.txt', is expanded here rather than by the shell.  " +
+	"Specifying both filename(s) and \"-\" will combine the contents of the file(s) and " +
+	"whatever is passed in via STDIN.  Files given with neither -candidates nor " +
+	"-components play both parts."}
+
+// flagHelp describes each flag at more length than its flag.Usage does.
+// Flags not described here make do with that.
+var flagHelp = map[string]string{
+	"h": "Prints this message.",
+	"d": "Names a word list, directory or glob to use as the dictionary.  May be repeated; " +
+		"the lists are combined.",
+	"stop": "Names a file of words which may never be used as components, such as stray " +
+		"single letters or suffixes like \"er\" and \"ed\".",
+	"allow": "Names a file of words which are the only ones that may be used as components.",
+	"strategy": "How to choose between the ways a word can be split: greedy takes the first " +
+		"one found, trying the longest prefixes first; probable takes the most probable one, " +
+		"and needs counts; and fewest takes the one with the fewest components.  The default " +
+		"is probable for a list with counts, and greedy otherwise.",
+	"candidates": "Names a file of words to check for compoundness, without making them " +
+		"available as components.  May be repeated.",
+	"components": "Names a file of words to build compound words out of, without checking " +
+		"them for compoundness.  May be repeated.",
+	"maxline": "The longest line, in bytes, to accept from a word list.  The default of 0 " +
+		"means lines may be any length at all.",
+	"oversize": "What to do with a line longer than -maxline: skip it, truncate it to " +
+		"-maxline bytes, or fail (the default).",
+	"include": "When walking a directory, only read files whose names match this glob.  " +
+		"May be repeated.",
+	"exclude": "When walking a directory, skip files and directories whose names match " +
+		"this glob.  May be repeated.",
+	"v": "Reports how many words were read from each file.",
+	"dups": "Reports every word which was found more than once, with the file and line of " +
+		"each time it was found.",
+	"format": "How to write the results: as text (the default), as a JSON array, as " +
+		"newline-delimited JSON, one object per line, or as comma- or tab-separated rows " +
+		"for spreadsheets and databases.  Text going to a terminal is laid out for people " +
+		"rather than scripts, the same as asking for human; results are aligned, and each " +
+		"component is colored differently from its neighbours.",
+	"color": "Whether human output is colored.  The default, auto, colors it only on a " +
+		"terminal, and only if NO_COLOR isn't set to anything.",
+	"template": "Writes each result through the given Go text/template in place of any " +
+		"-format.  It can use .Word, .Length, .Runes, .Compound, .Components, .Parts (how " +
+		"many components), .Offsets, .Score, .Cost, .Source and .Sources, and the functions join, " +
+		"upper, lower and title; {{join .Components \"-\"}} gives \"foo-bar\", for instance.",
+	"sep": "The separator for csv and tsv rows, if not ',' or tab.",
+	"join": "What joins the components of a word in the last column of csv and tsv rows, " +
+		"if not '+'.  Pick something no word contains, such as a space, if words like c++ " +
+		"are on the lists.",
+	"header": "Whether csv and tsv output starts with a row of column names.  It does " +
+		"unless given -header=false.",
+	"n": "How many of the longest compound words to report.",
+	"history": "The file to keep the lines typed in, from one session to the next.  The " +
+		"default is .compound_history in the current directory; -history \"\" keeps no file.",
+	"addr":  "The address to listen on; :8080 by default.",
+	"limit": "The most completions to list; 10 by default, and 0 for all of them.",
+	"order": "The order to list completions in: alpha, the default, or frequency, by the " +
+		"counts given in the lists, the highest first.",
+	"compound": "Completes the next component of a compound word, rather than the word itself.",
+	"fuzzy": "How many edits each component of a word may be from the word it's taken for, " +
+		"so that misspellings can still be split.  The default of 0 means they must match " +
+		"exactly; a word which does is never read as a misspelling.",
+	"distance": "How edits are counted for -fuzzy: levenshtein, the default, counts inserting, " +
+		"deleting or changing a letter as one edit; damerau also counts swapping two " +
+		"neighbouring letters as one.",
+	"old": "Names a word list, directory or glob to compare from.  May be repeated; the " +
+		"lists are combined.",
+	"new": "Names a word list, directory or glob to compare to.  May be repeated; the lists " +
+		"are combined.",
+	"json": "Writes the differences as a JSON object, with appeared, disappeared, changed, " +
+		"longest and counts members, in place of text.",
+	"count": "How many names to propose; 10 by default.  Fewer may be, if the rest are " +
+		"too hard to find.",
+	"parts": "The most words to join together into one; 2 by default.  generate joins 2 or " +
+		"3, and rack, given 1, lists single words only.",
+	"min":     "The shortest name to propose, in bytes.",
+	"max":     "The longest name to propose, in bytes.  The default of 0 means there's no limit.",
+	"pattern": "A regular expression which the names must match, such as ^sun or ly$.",
+	"avoid": "Names a file of unwanted words, none of which may appear anywhere in a name, " +
+		"even spanning the words it was made from.",
+	"seed": "Seeds the random choice of words; the same seed always gives the same names.",
+}
+
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// A replacement is the command which does what a flag used to, before
+// compound had commands, and whether it takes the word the flag did.
+type replacement struct {
+	command   string
+	takesWord bool
+}
+
+// deprecatedFlags are the flags which used to pick what compound did.
+// They still work, with a warning, by running the command in their place.
+var deprecatedFlags = map[string]replacement{
+	"stats":     {"stats", false},
+	"explain":   {"explain", true},
+	"dot":       {"dot", true},
+	"coprocess": {"coprocess", false},
+}
+
+// undeprecate finds a flag from deprecatedFlags among args, and if there
+// is one, warns about it, and returns the command which replaced it with
+// the arguments to run it with: the rest of args, and the flag's word, if
+// it took one, after the other flags.
+func undeprecate(args []string) (c *command, rest []string, found bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		r, deprecated := deprecatedFlags[name]
+		if !deprecated {
+			continue
+		}
+
+		rest = append(append([]string{}, args[:i]...), args[i+1:]...)
+		if !r.takesWord && hasValue {
+			if on, err := strconv.ParseBool(value); err == nil && !on {
+				return findCommand("longest"), rest, true
+			}
+		}
+		programName := filepath.Base(os.Args[0])
+		fmt.Fprintf(os.Stderr, "%s: -%s is deprecated; use \"%s %s\" instead.\n",
+			programName, name, programName, r.command)
+		c = findCommand(r.command)
+		if !r.takesWord {
+			return c, rest, true
+		}
+
+		w := value
+		if !hasValue {
+			if i+1 >= len(args) {
+				// Leave the command to complain about the missing word.
+				return c, rest, true
+			}
+			w = args[i+1]
+			rest = append(append([]string{}, args[:i]...), args[i+2:]...)
+		}
+		// The word has to come after the flags, or they won't be parsed.
+		fs, _ := c.flagSet()
+		fs.SetOutput(io.Discard)
+		fs.Usage = func() {}
+		flags := 0
+		if fs.Parse(rest) == nil {
+			flags = len(rest) - fs.NArg()
+		}
+		rest = append(append(append([]string{}, rest[:flags]...), w), rest[flags:]...)
+		return c, rest, true
+	}
+	return nil, args, false
+}
+
+// flagSet returns a flag set with the command's flags registered on it,
+// and what the command does once they've been parsed.
+func (c *command) flagSet() (*flag.FlagSet, action) {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs, c.flags(fs)
+}
+
+// run parses the command's flags out of args, and then does what it does.
+func (c *command) run(args []string) int {
+	fs, act := c.flagSet()
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage(c)) }
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return exitSuccess
+	} else if err != nil {
+		return exitUsage
+	}
+	return act(fs)
+}
+
+// misuse says what's wrong with the command line, and how to use it
+// instead, and returns the status to exit with.
+func misuse(fs *flag.FlagSet, err error) int {
+	fmt.Fprintf(os.Stderr, "%s.\n", strings.ToUpper(err.Error()[:1])+err.Error()[1:])
+	fs.Usage()
+	return exitUsage
+}
+
+// setUpSearch checks the flags of a search, then loads the word lists
+// named by files.  If either goes wrong, it says so, and returns the
+// status to exit with in place of a search.
+func setUpSearch(fs *flag.FlagSet, sf searchFlags, files []string) (*search, int) {
+	if err := sf.finish(files); err != nil {
+		return nil, misuse(fs, err)
+	}
+	s, err := loadSearch(files, sf)
+	if err != nil {
+		return nil, cantLoad(fs, err)
+	}
+	return s, exitSuccess
+}
+
+// cantLoad says why word lists couldn't be loaded, and returns the status
+// to exit with.  Asking for a strategy the lists have no counts for is the
+// command line's fault; anything else is an I/O error.
+func cantLoad(fs *flag.FlagSet, err error) int {
+	if err == errNeedsCounts {
+		return misuse(fs, err)
+	}
+	complain(err)
+	return exitIO
+}
+
+// report writes the results, and returns the status to exit with.
+func report(format formatter, results potentials) int {
+	if err := format(os.Stdout, results); err != nil {
+		complain(err)
+		return exitIO
+	}
+	// split reports words which aren't compound too, so there being
+	// results doesn't mean any compound word was found.
+	for _, p := range results {
+		if len(p.components) > 0 {
+			return exitSuccess
+		}
+	}
+	fmt.Fprintln(os.Stderr, "No compound words found.")
+	return exitNoCompound
+}
+
+// searchAndReport is the flags of a command which searches word lists
+// and reports on what it finds, with find saying what that is.
+func searchAndReport(fs *flag.FlagSet, find func(s *search) potentials) action {
+	var sf searchFlags
+	sf.register(fs)
+	var outOpts outputOptions
+	outOpts.register(fs)
+	return func(fs *flag.FlagSet) int {
+		format, err := outOpts.formatterFor(os.Stdout)
+		if err != nil {
+			return misuse(fs, fmt.Errorf("bad output settings: %v", err))
+		}
+		s, status := setUpSearch(fs, sf, fs.Args())
+		if s == nil {
+			return status
+		}
+		return report(format, s.withSources(find(s)))
+	}
+}
+
+func longestFlags(fs *flag.FlagSet) action {
+	n := fs.Int("n", 1, "how many of the longest compound words to report")
+	return searchAndReport(fs, func(s *search) potentials {
+		if *n < 1 {
+			return nil
+		}
+		return longestCompounds(s.byLength, s.dict, *n)
+	})
+}
+
+func allFlags(fs *flag.FlagSet) action {
+	return searchAndReport(fs, func(s *search) potentials {
+		return longestCompounds(s.byLength, s.dict, 0)
+	})
+}
+
+func splitFlags(fs *flag.FlagSet) action {
+	return searchAndReport(fs, func(s *search) potentials {
+		results, _ := checkWords(s.readOrder, s.dict)
+		return results
+	})
+}
+
+// usage describes the command c, working from its flags, or if c is nil,
+// lists all the commands.
+func usage(c *command) string {
+	programName := filepath.Base(os.Args[0])
+	if c == nil {
+		return overview(programName)
+	}
+
+	fs, _ := c.flagSet()
+	synopsis := []string{"[-h]"}
+	var rows []operand
+	rows = append(rows, operand{"-h", flagHelp["h"]})
+	fs.VisitAll(func(f *flag.Flag) {
+		arg, short := flag.UnquoteUsage(f)
+		if _, isBool := f.Value.(interface{ IsBoolFlag() bool }); isBool {
+			arg = ""
+		}
+		synopsis = append(synopsis, strings.TrimSpace("[-"+f.Name+" "+arg)+"]")
+		help, described := flagHelp[f.Name]
+		if !described {
+			help = strings.ToUpper(short[:1]) + short[1:] + "."
+		}
+		rows = append(rows, operand{"-" + f.Name, help})
+	})
+	if c.operands != "" {
+		synopsis = append(synopsis, c.operands)
+	}
+	rows = append(rows, c.takes...)
+
+	u := wrapSynopsis("Usage: "+programName+" "+c.name, synopsis) +
+		"\tWhere:\n"
+	for _, row := range rows {
+		u += describe(row)
+	}
+	u += "\n" + strings.Join(wrap(c.about, 79), "\n") + "\n\n"
+	return u
+}
+
+// overview is the usage for compound as a whole: what each command is for.
+func overview(programName string) string {
+	u := "Usage: " + programName + " command [flags] [arguments]\n" +
+		"       " + programName + " [flags] < - | filename [filename ...] >\n" +
+		"\tWhere command is one of:\n"
+	for _, c := range commands {
+		u += describe(operand{c.name, c.summary})
+	}
+	return u + "\n" +
+		"Without a command, " + programName + " does what the longest command does.\n" +
+		"\"" + programName + " help command\" or \"" + programName + " command -h\" describes a command\n" +
+		"and its flags.  The old flags -stats, -explain, -dot and -coprocess are\n" +
+		"deprecated, and run the commands of the same names.\n" +
+		"\n" +
+		"Whether in a stream or in file(s), words are expected to be given one per line.\n" +
+		"A line may also give a count for its word, separated from it by a tab, in which\n" +
+		"case the most probable decomposition of a compound word is reported.\n" +
+		"\n" +
+		"Exits with 0 if a compound word was found, 1 if none was, 2 if the command line\n" +
+		"was unusable, and 3 if a word list could not be read.\n" +
+		"\n"
+}
+
+// describe lays out a flag, operand or command and what it's for, with
+// the names lined up on the colons, in the same way usage always has.
+func describe(row operand) string {
+	const nameEnds = 24 // Columns, given eight to a tab.
+	var name string
+	if len(row.name) <= nameEnds-16 {
+		name = "\t\t" + strings.Repeat(" ", nameEnds-16-len(row.name)) + row.name
+	} else {
+		name = "\t" + strings.Repeat(" ", max(nameEnds-8-len(row.name), 0)) + row.name
+	}
+	lines := wrap(row.help, 79-nameEnds-3)
+	return name + " : " + strings.Join(lines, "\n\t\t           ") + "\n"
+}
+
+// wrapSynopsis lays out the parts of a synopsis after its first line, in
+// as few lines as will fit.
+func wrapSynopsis(first string, parts []string) (s string) {
+	line, width := first, len(first)
+	for _, p := range parts {
+		if width+1+len(p) > 79 {
+			s += line + "\n"
+			line, width = "\t\t"+p, 16+len(p)
+			continue
+		}
+		line += " " + p
+		width += 1 + len(p)
+	}
+	return s + line + "\n"
+}
+
+// wrap breaks text into lines of at most width bytes, at spaces.  Two
+// spaces after a sentence are kept, unless a line breaks there.
+func wrap(text string, width int) (lines []string) {
+	line := ""
+	for _, sentence := range strings.Split(text, ".  ") {
+		if line != "" {
+			line += "."
+		}
+		for i, w := range strings.Fields(sentence) {
+			gap := " "
+			if i == 0 && line != "" {
+				gap = "  "
+			}
+			if line == "" {
+				line = w
+			} else if len(line)+len(gap)+len(w) > width {
+				lines = append(lines, line)
+				line = w
+			} else {
+				line += gap + w
+			}
+		}
+	}
+	return append(lines, line)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// capture runs f with STDOUT and STDERR going to files of their own, and
+// returns what was written to them.
+func capture(t *testing.T, f func()) (stdout, stderr string) {
+	dir := t.TempDir()
+	out, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	defer errs.Close()
+
+	realOut, realErr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = out, errs
+	defer func() { os.Stdout, os.Stderr = realOut, realErr }()
+	f()
+
+	for _, f := range []*os.File{out, errs} {
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o, _ := io.ReadAll(out)
+	e, _ := io.ReadAll(errs)
+	return string(o), string(e)
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "test.list")
+	var content string
+	for _, w := range testWords {
+		content += string(w) + "\n"
+	}
+	if err := os.WriteFile(list, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.list")
+	simple := filepath.Join(dir, "simple.list")
+	if err := os.WriteFile(simple, []byte("foo\nbar\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unsorted := filepath.Join(dir, "unsorted.list")
+	if err := os.WriteFile(unsorted, []byte("foo\nbar\nfoobar\nbaz\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var rTests = []struct {
+		args    []string
+		status  int
+		mention string
+	}{
+		{[]string{list}, exitSuccess, "barfooquux = bar + foo + quux\n"},
+		{[]string{"longest", list}, exitSuccess, "barfooquux = bar + foo + quux\n"},
+		{[]string{"longest", "-n", "2", list}, exitSuccess, "foobar = foo + bar\n"},
+		{[]string{"all", list}, exitSuccess, "quart = qu + art\n"},
+		{[]string{"split", list}, exitSuccess, "artful [NOT COMPOUND]\n"},
+		{[]string{"split", simple}, exitNoCompound, "foo [NOT COMPOUND]\n"},
+		{[]string{"split", unsorted}, exitSuccess,
+			"foo [NOT COMPOUND]\nbar [NOT COMPOUND]\nfoobar = foo + bar\nbaz [NOT COMPOUND]\n"},
+		{[]string{"-format", "ndjson", list}, exitSuccess, `{"word":"barfooquux"`},
+		{[]string{"check", "-d", list, "quartsplat"}, exitSuccess, "quartsplat = quart + splat"},
+		{[]string{"check", "-d", list, "fibble"}, exitNoCompound, "fibble [NOT COMPOUND]"},
+		{[]string{"stats", list}, exitSuccess, "Graph nodes:"},
+		{[]string{"explain", "quartsplat", list}, exitSuccess, "Result: quartsplat = quart + splat"},
+		{[]string{"dot", "fibble", list}, exitNoCompound, `digraph "fibble"`},
+		{[]string{"-stats", list}, exitSuccess, "Graph nodes:"},
+		{[]string{"-explain", "quartsplat", list}, exitSuccess, "Result: quartsplat = quart + splat"},
+		{[]string{"-v", "--explain=quartsplat", list}, exitSuccess, "Result: quartsplat = quart + splat"},
+		{[]string{"--dot", "fibble", list}, exitNoCompound, `digraph "fibble"`},
+		{[]string{"-stats=false", list}, exitSuccess, "barfooquux = bar + foo + quux\n"},
+		{[]string{"help", "check"}, exitSuccess, "Usage: "},
+		{[]string{"help"}, exitSuccess, "Where command is one of:"},
+		{[]string{"-h"}, exitSuccess, ""},
+		{[]string{"longest", "-h"}, exitSuccess, ""},
+		{[]string{}, exitUsage, ""},
+		{[]string{"-bogus", list}, exitUsage, ""},
+		{[]string{"longest"}, exitUsage, ""},
+		{[]string{"-strategy", "probable", list}, exitUsage, ""},
+		{[]string{"-strategy", "silly", list}, exitUsage, ""},
+		{[]string{"check", "quartsplat"}, exitUsage, ""},
+		{[]string{"explain"}, exitUsage, ""},
+		{[]string{"coprocess", "-"}, exitUsage, ""},
+		{[]string{"help", "bogus"}, exitUsage, ""},
+		{[]string{missing}, exitIO, ""},
+		{[]string{"serve", "-d", missing}, exitIO, ""},
+	}
+
+	for _, tst := range rTests {
+		var status int
+		stdout, stderr := capture(t, func() { status = run(tst.args) })
+		if status != tst.status {
+			t.Errorf("run - %q: Expected status %d, got %d\n%s", tst.args, tst.status, status, stderr)
+		}
+		if !strings.Contains(stdout, tst.mention) {
+			t.Errorf("run - %q: Missing %q from\n%s", tst.args, tst.mention, stdout)
+		}
+	}
+}
+
+func TestFindCommand(t *testing.T) {
+	for _, name := range []string{"longest", "all", "split", "check", "stats", "serve", "help"} {
+		if c := findCommand(name); c == nil || c.name != name {
+			t.Errorf("findCommand - Couldn't find %s", name)
+		}
+	}
+	if c := findCommand("bogus"); c != nil {
+		t.Errorf("findCommand - Found %s for bogus", c.name)
+	}
+}
+
+// columns is how wide a line is, given eight columns to a tab.
+func columns(line string) (n int) {
+	for _, r := range line {
+		if r == '\t' {
+			n += 8 - n%8
+		} else {
+			n++
+		}
+	}
+	return
+}
+
+func TestCommandUsage(t *testing.T) {
+	for _, c := range append(commands, nil) {
+		u := usage(c)
+		if !strings.HasPrefix(u, "Usage: ") {
+			t.Errorf("usage - %v doesn't start with Usage:\n%s", c, u)
+		}
+		for _, line := range strings.Split(u, "\n") {
+			if columns(line) > 80 {
+				t.Errorf("usage - Line too long: %q", line)
+			}
+			// Everything described should line up on the colon.
+			if i := strings.Index(line, " : "); i >= 0 && strings.HasPrefix(line, "\t") && columns(line[:i]) != 24 {
+				t.Errorf("usage - Misaligned: %q", line)
+			}
+		}
+		if c == nil {
+			continue
+		}
+		// Every flag should be both in the synopsis and described.
+		fs, _ := c.flagSet()
+		fs.VisitAll(func(f *flag.Flag) {
+			if !strings.Contains(u, "[-"+f.Name) || !strings.Contains(u, "-"+f.Name+" : ") {
+				t.Errorf("usage - %s doesn't describe -%s:\n%s", c.name, f.Name, u)
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	var dTests = []struct {
+		row    operand
+		expect string
+	}{
+		{operand{"-v", "Talks."}, "\t\t      -v : Talks.\n"},
+		{operand{"-oversize", "Chops."}, "\t       -oversize : Chops.\n"},
+		{operand{"-candidates", "Checks."}, "\t     -candidates : Checks.\n"},
+		{operand{"word", strings.Repeat("blah ", 12)}, "\t\t    word : " + strings.Repeat("blah ", 9) +
+			"blah\n\t\t           blah blah\n"},
+	}
+
+	for _, tst := range dTests {
+		if actual := describe(tst.row); actual != tst.expect {
+			t.Errorf("describe - Expected\n\t%q\nBut got\n\t%q", tst.expect, actual)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	var wTests = []struct {
+		text   string
+		width  int
+		expect []string
+	}{
+		{"one two three", 80, []string{"one two three"}},
+		{"one two three", 7, []string{"one two", "three"}},
+		{"One.  Two three.", 80, []string{"One.  Two three."}},
+		{"One two.  Three.", 8, []string{"One two.", "Three."}},
+		{"", 10, []string{""}},
+	}
+
+	for _, tst := range wTests {
+		if actual := wrap(tst.text, tst.width); strings.Join(actual, "|") != strings.Join(tst.expect, "|") {
+			t.Errorf("wrap - Expected %q but got %q", tst.expect, actual)
+		}
+	}
+}
+
+func TestWrapSynopsis(t *testing.T) {
+	actual := wrapSynopsis("Usage: x", []string{strings.Repeat("a", 60), strings.Repeat("b", 30), "c"})
+	expected := "Usage: x " + strings.Repeat("a", 60) + "\n\t\t" + strings.Repeat("b", 30) + " c\n"
+	if actual != expected {
+		t.Errorf("wrapSynopsis - Expected\n\t%q\nBut got\n\t%q", expected, actual)
+	}
+}
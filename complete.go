@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// The orders completions can come in.
+const (
+	orderAlpha     = "alpha"
+	orderFrequency = "frequency"
+)
+
+// completions returns up to limit of the words on d's graph which begin
+// with prefix, or all of them if limit is less than one.  They come in
+// order, or by frequency, the one with the highest count first and those
+// with the same count in order.  With componentsOnly, words which d
+// doesn't allow as components are left out.
+func completions(d dictionary, prefix word, limit int, order string, componentsOnly bool) (ws words) {
+	if order == orderAlpha && !componentsOnly {
+		return d.graph.WordsWithPrefix(prefix, limit)
+	}
+
+	type counted struct {
+		w      word
+		weight int
+	}
+	var found []counted
+	d.graph.visitFrom(prefix, func(w word, weight int) bool {
+		if !componentsOnly || d.isComponent(w) {
+			found = append(found, counted{w, weight})
+		}
+		// Which are the most frequent isn't known until they've all been
+		// seen.
+		return order == orderFrequency || limit < 1 || len(found) < limit
+	})
+	if order == orderFrequency {
+		sort.SliceStable(found, func(i, j int) bool { return found[i].weight > found[j].weight })
+	}
+	for _, c := range found {
+		if limit > 0 && len(ws) == limit {
+			break
+		}
+		ws = append(ws, c.w)
+	}
+	return
+}
+
+// leadingComponents returns the components text is made of, if it's made
+// of components at all: just itself if it's one, or else the fewest it
+// can be split into.
+func leadingComponents(text word, d dictionary) words {
+	if d.isComponent(text) {
+		return words{text}
+	}
+	return fewestSplit(text, d)
+}
+
+// completeCompound treats text as a compound word in the middle of being
+// typed.  It keeps a beginning of text which is made of whole components,
+// and completes what's left after it as the next component; nothing left
+// at all means text ends between components, so any component can come
+// next.  Of the beginnings which leave something that can be completed,
+// the one made of the fewest components wins, and then the longest, so
+// that "firehou" is read as fire + hou... rather than fire + ho + u....
+// It returns up to limit compound words, with their components, in the
+// order their last components come in.
+func completeCompound(text word, d dictionary, limit int, order string) (results potentials) {
+	type beginning struct {
+		length int
+		parts  words
+	}
+	var beginnings []beginning
+	for i := 1; i <= len(text); i++ {
+		if lead := leadingComponents(text[:i], d); lead != nil {
+			beginnings = append(beginnings, beginning{i, lead})
+		}
+	}
+	sort.Slice(beginnings, func(i, j int) bool {
+		bi, bj := beginnings[i], beginnings[j]
+		if len(bi.parts) != len(bj.parts) {
+			return len(bi.parts) < len(bj.parts)
+		}
+		return bi.length > bj.length
+	})
+
+	for _, b := range beginnings {
+		for _, c := range completions(d, text[b.length:], limit, order, true) {
+			whole := append(append(word(nil), text[:b.length]...), c...)
+			parts := append(append(words(nil), b.parts...), c)
+			results = append(results, potential{whole: whole, components: parts})
+		}
+		if len(results) > 0 {
+			return
+		}
+	}
+	return
+}
+
+// completeFlags are the flags of the complete command, which takes the
+// beginning of a word, and then the word lists to complete it from.
+func completeFlags(fs *flag.FlagSet) action {
+	limit := fs.Int("limit", 10, "most `completions` to list (0 for all of them)")
+	order := fs.String("order", orderAlpha, "order to list completions in: `alpha|frequency`")
+	compound := fs.Bool("compound", false, "complete the next component of a compound word")
+	var df dictionaryFlags
+	df.register(fs)
+
+	return func(fs *flag.FlagSet) int {
+		if fs.NArg() == 0 {
+			return misuse(fs, errors.New("nothing to complete"))
+		}
+		if fs.NArg() == 1 {
+			return misuse(fs, errors.New("no word lists given"))
+		}
+		if *order != orderAlpha && *order != orderFrequency {
+			return misuse(fs, fmt.Errorf("unknown -order %q (try %s or %s)", *order, orderAlpha, orderFrequency))
+		}
+		if err := df.finish(); err != nil {
+			return misuse(fs, err)
+		}
+
+		dict, _, err := loadDictionary(fs.Args()[1:], df)
+		if err != nil {
+			return cantLoad(fs, err)
+		}
+		text := word(fs.Arg(0))
+		var lines []string
+		if *compound {
+			for _, p := range completeCompound(text, dict, *limit, *order) {
+				lines = append(lines, p.String())
+			}
+		} else {
+			for _, w := range completions(dict, text, *limit, *order, false) {
+				lines = append(lines, string(w))
+			}
+		}
+
+		for _, line := range lines {
+			if _, err = fmt.Println(line); err != nil {
+				complain(err)
+				return exitIO
+			}
+		}
+		if len(lines) == 0 {
+			fmt.Fprintf(os.Stderr, "Nothing on the lists begins with %q.\n", text)
+			return exitNoCompound
+		}
+		return exitSuccess
+	}
+}
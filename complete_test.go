@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompletions(t *testing.T) {
+	d := dictionary{graph: graphOf(testWords, weights{"quux": 5, "quart": 9}), minLen: 2, total: 14}
+	var cTests = []struct {
+		limit    int
+		order    string
+		expected words
+	}{
+		{0, orderAlpha, words{word("qu"), word("quart"), word("quux")}},
+		{0, orderFrequency, words{word("quart"), word("quux"), word("qu")}},
+		{2, orderFrequency, words{word("quart"), word("quux")}},
+	}
+	for _, ct := range cTests {
+		if actual := completions(d, word("qu"), ct.limit, ct.order, false); !reflect.DeepEqual(actual, ct.expected) {
+			t.Errorf("completions - For %d by %s, expected %q, got %q", ct.limit, ct.order, ct.expected, actual)
+		}
+	}
+
+	stop := graphOf(words{word("quart")}, nil)
+	d.stop = &stop
+	expected := words{word("qu"), word("quux")}
+	if actual := completions(d, word("qu"), 0, orderAlpha, true); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("completions - With quart stopped, expected %q, got %q", expected, actual)
+	}
+}
+
+func TestCompleteCompound(t *testing.T) {
+	var cTests = []struct {
+		text     word
+		limit    int
+		expected []string
+	}{
+		// foobar is one component, and longer than foo.
+		{word("foobarqu"), 0, []string{
+			"foobarqu = foobar + qu", "foobarquart = foobar + quart", "foobarquux = foobar + quux",
+		}},
+		{word("splatf"), 1, []string{"splatfoo = splat + foo"}},
+		// Nothing on the list begins with "t" or "artt".
+		{word("quartt"), 0, nil},
+		{word("zz"), 0, nil},
+	}
+	for _, ct := range cTests {
+		var actual []string
+		for _, p := range completeCompound(ct.text, testDict, ct.limit, orderAlpha) {
+			actual = append(actual, p.String())
+		}
+		if !reflect.DeepEqual(actual, ct.expected) {
+			t.Errorf("completeCompound - For %s, expected %q, got %q", ct.text, ct.expected, actual)
+		}
+	}
+}
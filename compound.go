@@ -9,18 +9,47 @@
 //
 // ---
 //
-// Usage: compound < -h | - | filename [filename ...] >
+// Usage: compound command [flags] [arguments]
+//        compound [flags] < - | filename [filename ...] >
 //
-// Where:
-//        -h : Prints this message.
-//         - : Indicates that words should be read from STDIN.
-//  filename : Specifies a file containing a list of words to read in.
-//             Specifying multiple files will cause compound to read them
-//             all in and work on the aggregate list.
-//             Specifying both filename(s) and "-" will combine the contents
-//             of the file(s) and whatever is passed in via STDIN.
+// Where command is one of:
+//     longest : Finds the longest compound word in the word lists.
+//         all : Lists every compound word in the word lists, longest first.
+//       split : Splits every word in the word lists, compound or not.
+//       check : Checks whether particular words are compound, given the
+//               word lists.
+//       stats : Reports facts about the word lists and their graph.
+//     explain : Shows why a word is or isn't compound.
+//         dot : Draws the ways a word can be split as a Graphviz DOT graph.
+//    generate : Proposes new compound words, made of words from the lists.
+//        rack : Lists the words which can be made from a rack of letters.
+//        diff : Compares the compound words of two sets of word lists.
+//    complete : Lists the words which begin with the given text.
+//   coprocess : Answers JSON requests on STDIN, one per line.
+//       shell : Loads the word lists once, then takes commands to question
+//               and change them.
+//       serve : Answers questions about the word lists over HTTP.
+//        help : Describes a command, or lists them all.
+//
+// Without a command, compound does what the longest command does, so
+// "compound word.list" still finds the longest compound word on the list.
+// "compound help command" or "compound command -h" describes a command and
+// its flags; each command's usage is generated from the flags it actually
+// takes, by usage() in commands.go.
+//
+// Files given with neither -candidates nor -components play both parts, so
+// "-candidates products.list -components english.list" answers the question
+// "which product names are made entirely of English words?", while
+// "-candidates products.list english.list" would also let product names be
+// built out of each other.
 //
 // Whether in a stream or in file(s), words are expected to be given one per line.
+// A line may also carry a count for its word, separated from it by a tab
+// ("word\t12345").  If any counts are given, competing decompositions of a
+// word are ranked by their unigram probability, and the most likely one wins.
+//
+// The exit status is 0 if a compound word was found, 1 if none was, 2 if the
+// command line was unusable, and 3 if a word list could not be read.
 //
 // ---
 //
@@ -39,26 +68,49 @@
 //     examined more closely to see if they are compound words.  A word
 //     which does *not* begin with another word on the graph *cannot* be
 //     a compound word (at least with respect to the current word list).
+//      * By the same token, a compound word has to *end* with another
+//        word, so any candidate whose endings are none of them on the
+//        graph is passed over without searching it.  See endsInWord().
 //
 //  3) Compound words are searched for in reverse order of size, so that
 //     the first word that is found which is a compound word ends the run.
 //
+//  4) If the word list is weighted, every way of splitting a candidate is
+//     considered, and the split whose components are jointly the most
+//     probable is the one reported.  See bestSplit() for the details.
+//
 package main
 
 import (
 	"bufio"
 	"bytes"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 )
 
 const (
 	maxInt = int(^uint(0) >> 1)
 )
 
+// Exit codes, so that scripts can branch on the result without having to
+// parse the output.  exitUsage matches what the flag package uses when it
+// can't make sense of the command line.
+const (
+	exitSuccess    = 0 // A compound word was found.
+	exitNoCompound = 1 // Everything worked, but nothing was compound.
+	exitUsage      = 2 // The command line didn't make sense.
+	exitIO         = 3 // A word list couldn't be read.
+)
+
 // I got tired of typing brackets pretty early on.
 type word []byte
 type words []word
@@ -118,18 +170,21 @@ func (ws words) Swap(i, j int) {
 // This becomes an important factor in finding out what words *might*
 // be compound words.
 //
+// The weight of an end-of-word node is the count given for that word in
+// a weighted word list, or zero if no count was given.
 type bytegraph struct {
 	endOfWord bool
+	weight    int
 	next      map[byte]bytegraph
 }
 
-// makegraph takes a word and a pointer to a pre-existing bytegraph
-// (populated or not), and populates the bytegraph accordingly (see
-// example above).  Note that accurately determining whether or not
+// makegraph takes a word, its weight, and a pointer to a pre-existing
+// bytegraph (populated or not), and populates the bytegraph accordingly
+// (see example above).  Note that accurately determining whether or not
 // a word has prefixes is dependent on the bytegraph already containing
 // those prefixes.  That is the reason the main bytegraph must be
 // populated from a sorted list of words.
-func makegraph(w word, g *bytegraph) (hasPrefixes bool) {
+func makegraph(w word, weight int, g *bytegraph) (hasPrefixes bool) {
 	if len(w) > 0 {
 		hasPrefixes = g.endOfWord
 		b := w[0]
@@ -138,28 +193,306 @@ func makegraph(w word, g *bytegraph) (hasPrefixes bool) {
 			ng = bytegraph{}
 			ng.next = make(map[byte]bytegraph)
 		}
-		hasPrefixes = makegraph(w[1:], &ng) || hasPrefixes
+		hasPrefixes = makegraph(w[1:], weight, &ng) || hasPrefixes
 		g.next[b] = ng
 	} else {
 		g.endOfWord = true
+		g.weight = weight
+	}
+	return
+}
+
+// unmakegraph takes a word back out of a bytegraph, along with any of the
+// nodes which led only to it, and reports whether it was there to remove.
+func unmakegraph(w word, g *bytegraph) (removed bool) {
+	if len(w) == 0 {
+		removed = g.endOfWord
+		g.endOfWord, g.weight = false, 0
+		return
+	}
+	ng, exists := g.next[w[0]]
+	if !exists {
+		return false
+	}
+	removed = unmakegraph(w[1:], &ng)
+	if !ng.endOfWord && len(ng.next) == 0 {
+		delete(g.next, w[0])
+	} else {
+		g.next[w[0]] = ng
+	}
+	return
+}
+
+// graphOf builds a bytegraph out of an arbitrary list of words, with
+// their weights taken from wts (which may be nil).  The prefix information
+// makegraph returns is only meaningful for a sorted list, but membership
+// via isWord works regardless of order.
+func graphOf(ws words, wts weights) (g bytegraph) {
+	g.next = make(map[byte]bytegraph)
+	for _, w := range ws {
+		_ = makegraph(w, wts[string(w)], &g)
 	}
 	return
 }
 
+// weights maps each word to the count it was given in a weighted word
+// list.  Words which were never given a count are simply not present.
+type weights map[string]int
+
+// total is the sum of all the counts, which is the denominator for
+// the probability of any one word.
+func (wts weights) total() (t int) {
+	for _, count := range wts {
+		t += count
+	}
+	return
+}
+
+// An occurrence is a place a word was found: a file, and a line in it.
+type occurrence struct {
+	file string
+	line int
+}
+
+func (o occurrence) String() string {
+	return fmt.Sprintf("%s:%d", o.file, o.line)
+}
+
+// A wordset is everything that's been loaded from the word lists so far:
+// each distinct word once, in the order it was first seen, its weight,
+// and every place it was found.  read counts every word loaded, including
+// the duplicates.
+type wordset struct {
+	list    words
+	weights weights
+	origins map[string][]occurrence
+	read    int
+}
+
+func newWordset() *wordset {
+	return &wordset{
+		list:    make(words, 0),
+		weights: make(weights),
+		origins: make(map[string][]occurrence),
+	}
+}
+
+// add records that w was found at o, and reports whether this is the
+// first time it has been seen.  Only then does it go on the list; a word
+// on the list twice would otherwise go into the graph twice, and be
+// picked up as its own prefix.
+func (ws *wordset) add(w word, count int, o occurrence) (isNew bool) {
+	key := string(w)
+	_, seen := ws.origins[key]
+	if !seen {
+		nw := make(word, len(w))
+		copy(nw, w)
+		ws.list = append(ws.list, nw)
+	}
+	ws.origins[key] = append(ws.origins[key], o)
+	if count > 0 {
+		ws.weights[key] += count
+	}
+	ws.read++
+	return !seen
+}
+
+// sources returns the files w was found in, each once, in the order it
+// was found in them.
+func (ws *wordset) sources(w word) (files []string) {
+	seen := make(map[string]bool)
+	for _, o := range ws.origins[string(w)] {
+		if !seen[o.file] {
+			seen[o.file] = true
+			files = append(files, o.file)
+		}
+	}
+	return
+}
+
+// remove forgets w entirely, and reports whether it was there to forget.
+func (ws *wordset) remove(w word) (wasThere bool) {
+	key := string(w)
+	if _, wasThere = ws.origins[key]; !wasThere {
+		return
+	}
+	for i := range ws.list {
+		if string(ws.list[i]) == key {
+			ws.list = append(ws.list[:i], ws.list[i+1:]...)
+			break
+		}
+	}
+	delete(ws.origins, key)
+	delete(ws.weights, key)
+	return
+}
+
+// clone makes a copy of ws which can be added to without affecting ws.
+func (ws *wordset) clone() *wordset {
+	c := newWordset()
+	c.list = append(c.list, ws.list...)
+	for w, count := range ws.weights {
+		c.weights[w] = count
+	}
+	for w, occ := range ws.origins {
+		c.origins[w] = append([]occurrence(nil), occ...)
+	}
+	c.read = ws.read
+	return c
+}
+
+// writeDuplicates lists each word which was found more than once, along
+// with every place it was found, like so:
+//   foo    word.list:1021  extra.list:7
+func writeDuplicates(out io.Writer, ws *wordset) {
+	for _, w := range ws.list {
+		occ := ws.origins[string(w)]
+		if len(occ) < 2 {
+			continue
+		}
+		fmt.Fprintf(out, "%s", w)
+		for _, o := range occ {
+			fmt.Fprintf(out, "\t%v", o)
+		}
+		fmt.Fprintln(out)
+	}
+}
+
+// A dictionary is everything the compound search needs to know about
+// the word list: the bytegraph built from it, the length of its shortest
+// word, and the sum of all its word weights.  A total of zero means the
+// list carried no weights, in which case the first decomposition found
+// is as good as any other.
+//
+// stop and allow, when set, narrow down which words from the graph may
+// be used as components; see isComponent().  fuzz, when set, lets the
+// components of a word be misspelled; see fuzzySplit().  trace, when
+// set, is told about every step of the search (see explain.go).
+// strategy, when set, overrides the usual choice between the
+// decompositions of a word; see chosenStrategy().
+type dictionary struct {
+	graph    bytegraph
+	minLen   int
+	total    int
+	stop     *bytegraph
+	allow    *bytegraph
+	trace    *tracer
+	strategy string
+	fuzz     fuzziness
+}
+
+// The ways of choosing between competing decompositions of a word.
+const (
+	// The first one found, trying the longest prefixes first.
+	strategyGreedy = "greedy"
+	// The most probable one; only meaningful for a weighted list.
+	strategyProbable = "probable"
+	// The one with the fewest components.
+	strategyFewest = "fewest"
+)
+
+var strategies = []string{strategyGreedy, strategyProbable, strategyFewest}
+
+// chosenStrategy is the strategy the dictionary searches with: whichever
+// it was given, or else the most probable decomposition for a weighted
+// list and the first one found for any other.
+func (d dictionary) chosenStrategy() string {
+	switch {
+	case d.strategy != "":
+		return d.strategy
+	case d.total > 0:
+		return strategyProbable
+	}
+	return strategyGreedy
+}
+
+// errNeedsCounts is the complaint about a strategy which only works for
+// a weighted list being asked of one without weights.
+var errNeedsCounts = fmt.Errorf("the %s strategy needs a word list with counts", strategyProbable)
+
+// checkStrategy makes sure the dictionary's strategy is one it can use.
+func (d dictionary) checkStrategy() error {
+	switch d.strategy {
+	case "", strategyGreedy, strategyFewest:
+		return nil
+	case strategyProbable:
+		if d.total == 0 {
+			return errNeedsCounts
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown strategy %q (try one of %s)", d.strategy, strings.Join(strategies, ", "))
+}
+
+// isComponent reports whether w may be used as part of a compound word:
+// it must be a word, must not be on the stop-list, and if there is an
+// allow-list, it must be on that.
+func (d dictionary) isComponent(w word) bool {
+	return isWord(w, d.graph) &&
+		(d.stop == nil || !isWord(w, *d.stop)) &&
+		(d.allow == nil || isWord(w, *d.allow))
+}
+
 // A 'potential' struct is used to hold a word once it has been
-// determined that it is possible for that word to be compound.
+// determined that it is possible for that word to be compound.  The
+// score is the log probability of the chosen components, and is only
+// set when the dictionary is weighted.  sources, the files the word was
+// found in, is filled in when it's time to report on the word.  For a
+// fuzzy match, the components are the words the pieces of the word were
+// taken for, offsets are where those pieces begin, and cost is how many
+// edits it took to get from one to the other.
 type potential struct {
 	whole      word
 	prefixes   words
 	components words
+	score      float64
+	sources    []string
+	offsets    []int
+	cost       int
 }
 type potentials []potential
 
 // isCompound is the entry point for the code that determines the central
-// question - whether or not a word is a compound word.
-func (p *potential) isCompound(g bytegraph, minLen int) bool {
+// question - whether or not a word is a compound word.  Only if it isn't
+// made of words exactly, and isn't a word itself, is a fuzzy dictionary
+// asked to read it as a misspelling of some: a word which is spelled
+// right needs no correcting.
+func (p *potential) isCompound(d dictionary) bool {
+	if p.isExactlyCompound(d) {
+		return true
+	}
+	if d.fuzz.maxEdits > 0 && !isWord(p.whole, d.graph) {
+		p.components, p.offsets, p.cost = fuzzySplit(p.whole, d)
+		return p.components != nil
+	}
+	return false
+}
+
+func (p *potential) isExactlyCompound(d dictionary) bool {
+	switch d.chosenStrategy() {
+	case strategyProbable:
+		parts, score := bestSplit(p.whole, d)
+		if parts == nil {
+			return false
+		}
+		p.components, p.score = parts, score
+		return true
+	case strategyFewest:
+		parts := fewestSplit(p.whole, d)
+		if parts == nil {
+			return false
+		}
+		p.components = parts
+		return true
+	}
+
 	for _, pfx := range p.prefixes {
-		parts := subWords(p.whole[len(pfx):], g, minLen)
+		if !d.isComponent(pfx) {
+			d.trace.prefix(pfx, false)
+			continue
+		}
+		d.trace.prefix(pfx, true)
+		parts := subWords(p.whole[len(pfx):], d)
 		if parts != nil {
 			p.components = make(words, 0)
 			p.components = append(append(p.components, pfx), parts...)
@@ -172,9 +505,14 @@ func (p *potential) isCompound(g bytegraph, minLen int) bool {
 // subWords takes a word or partial word and returns all the words that
 // go together to make it up, but only if the word *can* be decomposed
 // into other words.  If w cannot be decomposed, ws will be nil.
-func subWords(w word, g bytegraph, minLen int) (ws words) {
+func subWords(w word, d dictionary) (ws words) {
+	minLen := d.minLen
+
 	// Obviously, if this is a word to start with, just return it.
-	if isWord(w, g) {
+	isWhole := d.isComponent(w)
+	d.trace.enter(w, isWhole)
+	defer d.trace.leave()
+	if isWhole {
 		return append(ws, w)
 	}
 
@@ -184,22 +522,26 @@ PRE:
 	for i := len(w) - minLen; i >= minLen; i-- {
 		pre, rest := w[:i], w[i:]
 		// If the prefix is a word...
-		if isWord(pre, g) {
+		if d.isComponent(pre) {
 			// ...then we check the remainder...
-			if isWord(rest, g) {
+			if d.isComponent(rest) {
 				// ...and if they're both words, we're done.
+				d.trace.tried(pre, rest, true, true)
 				ws = append(ws, pre, rest)
 				break PRE
 			} else {
 				// If the remainder is not a word on its own, check
 				// and see if it is composed of other words.
-				moar := subWords(rest, g, minLen)
+				d.trace.tried(pre, rest, true, false)
+				moar := subWords(rest, d)
 				if moar != nil {
 					// And again, if it is, we have our answer.
 					ws = append(append(ws, pre), moar...)
 					break PRE
 				}
 			}
+		} else {
+			d.trace.tried(pre, rest, false, false)
 		}
 	}
 
@@ -209,6 +551,106 @@ PRE:
 	return
 }
 
+// bestSplit finds the most probable way of building w entirely out of
+// at least two other words, scoring each way by the sum of the log
+// probabilities of its components.  A rare-word parse thereby loses to
+// the common-word parse, and since every extra component costs another
+// factor of probability, so does a parse with needlessly many pieces.
+// Words on the list without a count of their own are treated as having
+// been seen once.  If w cannot be decomposed, ws will be nil.
+//
+// Rather than trying every combination, this walks the graph once from
+// each position in w that some decomposition can reach, so best[i] always
+// holds the score of the most probable way to build w[:i], and from[i]
+// where the last word in that way begins.
+func bestSplit(w word, d dictionary) (ws words, score float64) {
+	n := len(w)
+	best := make([]float64, n+1)
+	from := make([]int, n+1)
+	for i := 1; i <= n; i++ {
+		best[i] = math.Inf(-1)
+	}
+	logTotal := math.Log(float64(d.total))
+
+	for j := 0; j < n; j++ {
+		if math.IsInf(best[j], -1) {
+			continue
+		}
+		g := d.graph
+	WALK:
+		for i := j; i < n; i++ {
+			next, exists := g.next[w[i]]
+			if !exists {
+				break WALK
+			}
+			g = next
+			// w itself doesn't count as one of its own components.
+			if !g.endOfWord || (j == 0 && i == n-1) || !d.isComponent(w[j:i+1]) {
+				continue WALK
+			}
+			count := g.weight
+			if count < 1 {
+				count = 1
+			}
+			s := best[j] + math.Log(float64(count)) - logTotal
+			if s > best[i+1] {
+				best[i+1], from[i+1] = s, j
+			}
+		}
+	}
+
+	if math.IsInf(best[n], -1) {
+		return nil, 0
+	}
+	for i := n; i > 0; i = from[i] {
+		ws = append(words{w[from[i]:i]}, ws...)
+	}
+	return ws, best[n]
+}
+
+// fewestSplit finds the way of building w entirely out of at least two
+// other words which uses the fewest of them, walking the graph the same
+// way bestSplit does.  Of several ways with the same number of words, the
+// first one found wins.  If w cannot be decomposed, ws will be nil.
+func fewestSplit(w word, d dictionary) (ws words) {
+	n := len(w)
+	fewest := make([]int, n+1)
+	from := make([]int, n+1)
+	for i := 1; i <= n; i++ {
+		fewest[i] = -1
+	}
+
+	for j := 0; j < n; j++ {
+		if fewest[j] < 0 {
+			continue
+		}
+		g := d.graph
+	WALK:
+		for i := j; i < n; i++ {
+			next, exists := g.next[w[i]]
+			if !exists {
+				break WALK
+			}
+			g = next
+			// w itself doesn't count as one of its own components.
+			if !g.endOfWord || (j == 0 && i == n-1) || !d.isComponent(w[j:i+1]) {
+				continue WALK
+			}
+			if fewest[i+1] < 0 || fewest[j]+1 < fewest[i+1] {
+				fewest[i+1], from[i+1] = fewest[j]+1, j
+			}
+		}
+	}
+
+	if fewest[n] < 0 {
+		return nil
+	}
+	for i := n; i > 0; i = from[i] {
+		ws = append(words{w[from[i]:i]}, ws...)
+	}
+	return ws
+}
+
 // Walk the graph and see if w is a word.
 func isWord(w word, g bytegraph) bool {
 	for _, b := range w {
@@ -224,6 +666,8 @@ func isWord(w word, g bytegraph) bool {
 
 // Returns either:
 //   foobar = foo + bar
+// - or, when the dictionary is weighted -
+//   foobar = foo + bar [log P = -17.25]
 // - or -
 //   foobar [NOT COMPOUND]
 func (p potential) String() string {
@@ -236,6 +680,12 @@ func (p potential) String() string {
 				s += " + "
 			}
 		}
+		if p.score != 0 {
+			s += fmt.Sprintf(" [log P = %.2f]", p.score)
+		}
+		if p.cost != 0 {
+			s += fmt.Sprintf(" [edit cost = %d]", p.cost)
+		}
 	} else {
 		s += " [NOT COMPOUND]"
 	}
@@ -243,59 +693,241 @@ func (p potential) String() string {
 	return s
 }
 
-// loadWordsFrom takes a stream of words and populates a simple list
-// of words.  It returns the length of the shortest word it sees.
-func loadWordsFrom(r io.Reader, wordlist *words) (minLen int) {
-	wordloader := bufio.NewScanner(r)
+// A lineLimit says how long a line of a word list may be, and what to do
+// with one that's longer than that.  A max of zero means no limit.
+type lineLimit struct {
+	max    int
+	policy string
+}
+
+// The policies a lineLimit may have for oversize lines.
+const (
+	oversizeSkip     = "skip"
+	oversizeTruncate = "truncate"
+	oversizeFail     = "fail"
+)
+
+func (lim lineLimit) valid() bool {
+	switch lim.policy {
+	case oversizeSkip, oversizeTruncate, oversizeFail:
+		return lim.max >= 0
+	}
+	return false
+}
+
+// readLine returns the next line from r, without its line ending, however
+// long it is.  bufio.Scanner would give up on anything over 64KiB.  If max
+// is non-zero, no more than max bytes of the line are kept, so a runaway
+// line can't eat all the memory; size is always the full length of the
+// line, though.  At the end of the stream, err is io.EOF.
+func readLine(r *bufio.Reader, max int) (line []byte, size int, err error) {
+	for {
+		piece, more, err := r.ReadLine()
+		if err != nil {
+			return line, size, err
+		}
+		size += len(piece)
+		if max == 0 {
+			line = append(line, piece...)
+		} else if room := max - len(line); room > 0 {
+			if len(piece) > room {
+				piece = piece[:room]
+			}
+			line = append(line, piece...)
+		}
+		if !more {
+			return line, size, nil
+		}
+	}
+}
+
+// loadWordsFrom takes a stream of words, called name, and adds them to
+// ws.  Any line of the form "word\tcount" has its count added to the
+// word's weight, blank lines are skipped, and lines longer than lim
+// allows are dealt with according to its policy.  It returns the length
+// of the shortest word it sees, or an error naming the line that couldn't
+// be read or made sense of.
+func loadWordsFrom(r io.Reader, name string, ws *wordset, lim lineLimit) (minLen int, err error) {
+	wordloader := bufio.NewReader(r)
 	minLen = maxInt
 
-	for wordloader.Scan() {
-		nw := make(word, len(wordloader.Bytes()))
-		copy(nw, wordloader.Bytes())
-		*wordlist = append(*wordlist, nw)
-		if len(nw) < minLen {
-			minLen = len(nw)
+	for lineNo := 1; ; lineNo++ {
+		line, size, readErr := readLine(wordloader, lim.max)
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return minLen, fmt.Errorf("line %d: %v", lineNo, readErr)
+		}
+
+		if lim.max > 0 && size > lim.max {
+			switch lim.policy {
+			case oversizeSkip:
+				continue
+			case oversizeFail:
+				return minLen, fmt.Errorf("line %d: entry is %d bytes long, over the limit of %d",
+					lineNo, size, lim.max)
+			}
+		}
+
+		// A blank line, or a count with no word in front of it, has no
+		// word to add.  An empty word would mark the root of the graph as
+		// the end of a word, and the search would never get anywhere.
+		if len(line) == 0 || line[0] == '\t' {
+			continue
+		}
+
+		count := 0
+		if tab := bytes.IndexByte(line, '\t'); tab >= 0 {
+			count, err = strconv.Atoi(string(bytes.TrimSpace(line[tab+1:])))
+			if err != nil || count < 0 {
+				return minLen, fmt.Errorf("line %d: bad count for word %q: %q",
+					lineNo, line[:tab], line[tab+1:])
+			}
+			line = line[:tab]
+		}
+
+		ws.add(line, count, occurrence{file: name, line: lineNo})
+		if len(line) < minLen {
+			minLen = len(line)
 		}
 	}
 
 	return
 }
 
-func loadAllTheWords(wordlist *words) (minLen int) {
+// loadOptions governs how word lists are found and read.  include and
+// exclude are globs which filter the files found by walking a directory
+// (see expandFiles), and if progress is set, a line saying how many words
+// came from each file is written to it.
+type loadOptions struct {
+	lineLimit
+	include  fileList
+	exclude  fileList
+	progress io.Writer
+}
+
+// expandFiles turns the word lists named on the command line into a list
+// of the actual files to read.  "-" stays as it is.  Anything else which
+// doesn't exist but looks like a glob pattern is expanded, since a quoted
+// pattern never got to the shell.  Directories, whether named directly or
+// matched by a pattern, are walked recursively; the include and exclude
+// globs are matched against the base names of what's found there.
+func expandFiles(args []string, opts loadOptions) (files []string, err error) {
+	for _, arg := range args {
+		paths := []string{arg}
+		if _, statErr := os.Stat(arg); statErr != nil && strings.ContainsAny(arg, "*?[") {
+			paths, err = filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", arg, err)
+			}
+			if len(paths) == 0 {
+				return nil, fmt.Errorf("%s: no files match", arg)
+			}
+		}
+
+		for _, path := range paths {
+			info, statErr := os.Stat(path)
+			if arg == "-" || statErr != nil || !info.IsDir() {
+				// Anything which can't be read will be complained about
+				// by loadAllTheWords.
+				files = append(files, path)
+				continue
+			}
+			err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if p != path && matchesAny(d.Name(), opts.exclude) {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if d.Type().IsRegular() && (len(opts.include) == 0 || matchesAny(d.Name(), opts.include)) {
+					files = append(files, p)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return
+}
+
+// matchesAny reports whether name matches any of the globs.
+func matchesAny(name string, globs []string) bool {
+	for _, glob := range globs {
+		if matched, _ := filepath.Match(glob, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// loadAllTheWords runs loadWordsFrom over each of the named files ("-"
+// being STDIN), after expanding any directories and glob patterns among
+// them.  Any error names the file it came from; os.Open and friends
+// already do that for themselves.
+func loadAllTheWords(args []string, ws *wordset, opts loadOptions) (minLen int, err error) {
 	// Setting this initially to the maximum possible so
 	// anything returned by loadWordsFrom() will be less.
 	minLen = maxInt
 
-	for _, arg := range os.Args[1:] {
+	files, err := expandFiles(args, opts)
+	if err != nil {
+		return
+	}
+
+	for _, arg := range files {
 		var file *os.File
-		var err error
+		readBefore, newBefore := ws.read, len(ws.list)
 
 		if arg == "-" {
 			file = os.Stdin
 		} else {
 			file, err = os.Open(arg)
 			if err != nil {
-				panic(err)
+				return
 			}
 		}
 
-		minLength := loadWordsFrom(file, wordlist)
+		minLength, loadErr := loadWordsFrom(file, displayName(arg), ws, opts.lineLimit)
 		if minLength < minLen {
 			minLen = minLength
 		}
 
 		if file != os.Stdin {
 			err = file.Close()
-			if err != nil {
-				panic(err)
-			}
+		}
+		if loadErr != nil {
+			return minLen, fmt.Errorf("%s: %v", displayName(arg), loadErr)
+		}
+		if err != nil {
+			return
+		}
+
+		if opts.progress != nil {
+			fmt.Fprintf(opts.progress, "%s: %d words, %d new\n",
+				displayName(arg), ws.read-readBefore, len(ws.list)-newBefore)
 		}
 	}
 
 	return
 }
 
-func graphAndFindCandidates(wordlist words) (g bytegraph, pm map[int]potentials) {
+// displayName is how a file from the command line is referred to in
+// messages.
+func displayName(file string) string {
+	if file == "-" {
+		return "<stdin>"
+	}
+	return file
+}
+
+func graphAndFindCandidates(wordlist words, wts weights) (g bytegraph, pm map[int]potentials) {
 	g = bytegraph{}
 	g.next = make(map[byte]bytegraph)
 
@@ -306,7 +938,7 @@ func graphAndFindCandidates(wordlist words) (g bytegraph, pm map[int]potentials)
 		// The only words we're really interested in examining further
 		// are those that begin with another word from the list.  No
 		// others can possibly be compound words.
-		hasPrefixes := makegraph(thisword, &g)
+		hasPrefixes := makegraph(thisword, wts[string(thisword)], &g)
 		if hasPrefixes {
 			np := potential{}
 			np.whole = make(word, len(thisword))
@@ -341,77 +973,386 @@ func graphAndFindCandidates(wordlist words) (g bytegraph, pm map[int]potentials)
 	return
 }
 
-//////////////
-//
-// And now, without any further ado...
-//
-func main() {
-
-	// We do need *something* to work with.
-	if len(os.Args) == 1 || (len(os.Args) == 2 && os.Args[1] == "-h") {
-		fmt.Fprintf(os.Stderr, usage())
-		os.Exit(0)
-	}
+// findCandidates is graphAndFindCandidates for the case where the words
+// being checked are not the words they may be built from.  Since the
+// candidates need not be on g at all, their prefixes can't come from
+// their neighbours in a sorted list; instead each candidate is walked
+// along g directly.  The potentials come out the same, prefixes longest
+// first.
+func findCandidates(candidates words, g bytegraph) (pm map[int]potentials) {
+	pm = make(map[int]potentials)
 
-	// First, load up whatever words are to be processed.
-	allwords := make(words, 0)
+	for _, thisword := range candidates {
+		pfxs := prefixesOf(thisword, g)
+		if len(pfxs) == 0 {
+			continue
+		}
 
-	// Recording the minimum word length makes the subword search a
-	// bit more efficient.  If the smallest word is three characters,
-	// there's no need to go looking for a two character word, for
-	// instance.
-	minWordLength := loadAllTheWords(&allwords)
+		np := potential{}
+		np.whole = make(word, len(thisword))
+		copy(np.whole, thisword)
+		np.prefixes = pfxs
+		pm[len(np.whole)] = append(pm[len(np.whole)], np)
+	}
 
-	// The words must be sorted in order for the algorithm to work.
-	sort.Sort(allwords)
+	return
+}
 
-	// chargraph is the main bytegraph, which allows for a very rapid
-	// determination of composite words.
-	//
-	// candidatesByLength is pretty much what it sounds like.  Potential
-	// compound words, indexed by word length.
-	//
-	// Since the purpose is finding the *longest* compound word, it
-	// makes sense to be able to look at candidate words in descending
-	// order of length.  We can stop at the first one found since it
-	// will by definition be the longest.
-	chargraph, candidatesByLength := graphAndFindCandidates(allwords)
+// endsInWord tells whether w ends with a word on g, not counting w
+// itself.  Beginning with a word is all it takes to be a candidate, but
+// the last component of a compound word has to be a word too, and
+// otherwise that isn't discovered until deep inside the search.
+func endsInWord(w word, g bytegraph) bool {
+	for i := 1; i < len(w); i++ {
+		if isWord(w[i:], g) {
+			return true
+		}
+	}
+	return false
+}
 
+// longestCompounds returns up to n of the candidates in pm which turn out
+// to be compound, longest first, or all of them if n is less than one.
+// Since the longest candidates are tried first, the search stops as soon
+// as it has found n of them.
+func longestCompounds(pm map[int]potentials, d dictionary, n int) (results potentials) {
 	var descendingLengths []int
-	for l := range candidatesByLength {
+	for l := range pm {
 		descendingLengths = append(descendingLengths, l)
 	}
 	sort.Sort(sort.Reverse(sort.IntSlice(descendingLengths)))
 
 POSSIBLE:
 	for _, l := range descendingLengths {
-		for _, w := range candidatesByLength[l] {
-			if (&w).isCompound(chargraph, minWordLength) {
-				fmt.Println(w)
-				break POSSIBLE
+		for _, w := range pm[l] {
+			if endsInWord(w.whole, d.graph) && (&w).isCompound(d) {
+				results = append(results, w)
+				if len(results) == n {
+					break POSSIBLE
+				}
 			}
 		}
 	}
+	return
+}
+
+// prefixesOf returns every word on g which w begins with, not counting w
+// itself, longest first.
+func prefixesOf(w word, g bytegraph) (pfxs words) {
+	for i := 0; i < len(w)-1; i++ {
+		next, exists := g.next[w[i]]
+		if !exists {
+			break
+		}
+		g = next
+		if g.endOfWord {
+			pfx := make(word, i+1)
+			copy(pfx, w[:i+1])
+			pfxs = append(words{pfx}, pfxs...)
+		}
+	}
+	return
 }
 
-// exitUsage - what it says on the tin.  Just print the basic usage, and
-// exit gracefully.
-func usage() (u string) {
-	programName := filepath.Base(os.Args[0])
+// visitFrom calls visit with every word on g which begins with prefix,
+// prefix itself included, in order, along with its weight, until visit
+// returns false.
+func (g bytegraph) visitFrom(prefix word, visit func(w word, weight int) bool) {
+	for _, b := range prefix {
+		next, exists := g.next[b]
+		if !exists {
+			return
+		}
+		g = next
+	}
 
-	u = "Usage: " + programName + " < -h | - | filename [filename ...] >\n" +
-		"\tWhere:\n" +
-		"\t\t      -h : Prints this message.\n" +
-		"\t\t       - : Indicates that words should be read from STDIN.\n" +
-		"\t\tfilename : Specifies a file containing a list of words to read in.\n" +
-		"\t\t           Specifying multiple files will cause " + programName + " to read " +
-		"them all in\n" +
-		"\t\t           and work on the aggregate list.\n" +
-		"\t\t           Specifying both filename(s) and \"-\" will combine the contents of\n" +
-		"\t\t           the file(s) and whatever is passed in via STDIN.\n" +
-		"\n" +
-		"Whether in a stream or in file(s), words are expected to be given one per line.\n" +
-		"\n"
+	w := append(word(nil), prefix...)
+	var walk func(g bytegraph) bool
+	walk = func(g bytegraph) bool {
+		if g.endOfWord && !visit(append(word(nil), w...), g.weight) {
+			return false
+		}
+		// Maps don't keep their keys in order, so they have to be put in
+		// it for the words to come out in order.
+		bs := make([]byte, 0, len(g.next))
+		for b := range g.next {
+			bs = append(bs, b)
+		}
+		sort.Slice(bs, func(i, j int) bool { return bs[i] < bs[j] })
+		for _, b := range bs {
+			w = append(w, b)
+			more := walk(g.next[b])
+			w = w[:len(w)-1]
+			if !more {
+				return false
+			}
+		}
+		return true
+	}
+	walk(g)
+}
 
+// WordsWithPrefix returns the words on g which begin with prefix, prefix
+// itself included, in order.  Only the first limit of them are found,
+// unless limit is less than one.
+func (g bytegraph) WordsWithPrefix(prefix word, limit int) (ws words) {
+	g.visitFrom(prefix, func(w word, weight int) bool {
+		ws = append(ws, w)
+		return limit < 1 || len(ws) < limit
+	})
 	return
 }
+
+// fileList collects the values of a flag which may be given more than
+// once, such as -candidates and -components.
+type fileList []string
+
+func (fl *fileList) String() string {
+	return strings.Join(*fl, ",")
+}
+
+func (fl *fileList) Set(file string) error {
+	*fl = append(*fl, file)
+	return nil
+}
+
+// loadWordGraph reads a stop- or allow-list into a bytegraph of its own.
+// No file, no list; that is signalled by returning nil.
+func loadWordGraph(file string, opts loadOptions) (*bytegraph, error) {
+	if file == "" {
+		return nil, nil
+	}
+	ws := newWordset()
+	if _, err := loadAllTheWords([]string{file}, ws, opts); err != nil {
+		return nil, err
+	}
+	g := graphOf(ws.list, nil)
+	return &g, nil
+}
+
+// dictionaryFlags are the command-line settings which govern how a
+// dictionary is read and built, shared by everything that builds one.
+type dictionaryFlags struct {
+	stop     string
+	allow    string
+	strategy string
+	load     loadOptions
+	verbose  bool
+}
+
+func (df *dictionaryFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&df.stop, "stop", "", "`file` of words never to use as components")
+	fs.StringVar(&df.allow, "allow", "", "`file` of the only words to use as components")
+	fs.StringVar(&df.strategy, "strategy", "", "how to choose between splits: `greedy|probable|fewest`")
+	fs.IntVar(&df.load.max, "maxline", 0, "longest line to accept, in `bytes` (0 for no limit)")
+	fs.StringVar(&df.load.policy, "oversize", oversizeFail, "what to do with longer lines: `skip|truncate|fail`")
+	fs.Var(&df.load.include, "include", "`glob` for the files to read when walking a directory")
+	fs.Var(&df.load.exclude, "exclude", "`glob` for the files and directories to skip when walking a directory")
+	fs.BoolVar(&df.verbose, "v", false, "report how many words were read from each file")
+}
+
+// finish checks the settings make sense once the flags have been parsed,
+// and works out the ones which follow from others.
+func (df *dictionaryFlags) finish() error {
+	if !df.load.valid() {
+		return fmt.Errorf("bad -maxline %d or -oversize %q", df.load.max, df.load.policy)
+	}
+	// Whether the lists have the counts a strategy needs isn't known
+	// until they've been read, but its name can be checked now.
+	if err := (dictionary{strategy: df.strategy, total: 1}).checkStrategy(); err != nil {
+		return fmt.Errorf("bad -strategy: %v", err)
+	}
+	if df.verbose {
+		df.load.progress = os.Stderr
+	}
+	return nil
+}
+
+// loadDictionary reads the named word lists, and builds a dictionary of
+// components out of them according to df.  The words themselves come
+// back too.  Nothing is sorted, since the dictionary is only used for
+// looking words up; graphAndFindCandidates is what needs sorted input.
+func loadDictionary(files []string, df dictionaryFlags) (d dictionary, ws *wordset, err error) {
+	ws = newWordset()
+	if d.minLen, err = loadAllTheWords(files, ws, df.load); err != nil {
+		return
+	}
+	d.graph = graphOf(ws.list, ws.weights)
+	d.total = ws.weights.total()
+	d.strategy = df.strategy
+	if err = d.checkStrategy(); err != nil {
+		return
+	}
+	if d.stop, err = loadWordGraph(df.stop, df.load); err != nil {
+		return
+	}
+	d.allow, err = loadWordGraph(df.allow, df.load)
+	return
+}
+
+// complain reports err to the user, without a stack trace to wade through.
+func complain(err error) {
+	fmt.Fprintf(os.Stderr, "%s: %v\n", filepath.Base(os.Args[0]), err)
+}
+
+// searchFlags are the flags of the commands which search the word lists
+// they're given: how to build the dictionary out of them, which part each
+// list plays, and whether to report the words found more than once.
+type searchFlags struct {
+	dictionaryFlags
+	candidates fileList
+	components fileList
+	dups       bool
+}
+
+func (sf *searchFlags) register(fs *flag.FlagSet) {
+	sf.dictionaryFlags.register(fs)
+	fs.Var(&sf.candidates, "candidates", "`file` of words to check, but not to build with")
+	fs.Var(&sf.components, "components", "`file` of words to build with, but not to check")
+	fs.BoolVar(&sf.dups, "dups", false, "report every word found more than once, and where")
+}
+
+// finish checks the settings make sense, given the word lists named as
+// arguments.
+func (sf *searchFlags) finish(files []string) error {
+	// We do need *something* to work with - words to check, and words to
+	// build them out of.
+	if len(files) == 0 && (len(sf.candidates) == 0 || len(sf.components) == 0) {
+		return errors.New("no word lists given")
+	}
+	return sf.dictionaryFlags.finish()
+}
+
+// A search is a set of word lists, loaded and ready to search: the
+// dictionary of components, the words which might be built out of them,
+// and which of those begin with a component, by length.  readOrder is
+// the words which might be built out of components, in the order they
+// were read, since the search may sort them.  sw has timed each step of
+// getting them ready.
+type search struct {
+	dict       dictionary
+	components *wordset
+	candidates *wordset
+	readOrder  words
+	byLength   map[int]potentials
+	sw         *stopwatch
+}
+
+// loadSearch loads the word lists named by files, and by the -candidates
+// and -components flags, and gets them ready to search.
+func loadSearch(files []string, sf searchFlags) (s *search, err error) {
+	opts := sf.load
+	s = &search{sw: newStopwatch(), components: newWordset()}
+
+	// Recording the minimum word length makes the subword search a
+	// bit more efficient.  If the smallest word is three characters,
+	// there's no need to go looking for a two character word, for
+	// instance.
+	minWordLength, err := loadAllTheWords(files, s.components, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.sw.lap("load")
+
+	// chargraph is the main bytegraph, which allows for a very rapid
+	// determination of composite words.
+	//
+	// byLength is pretty much what it sounds like.  Potential compound
+	// words, indexed by word length.
+	var chargraph bytegraph
+	s.candidates = s.components
+
+	if len(sf.candidates) == 0 && len(sf.components) == 0 {
+		// The words must be sorted in order for the algorithm to work.
+		s.readOrder = append(words(nil), s.components.list...)
+		sort.Sort(s.components.list)
+		s.sw.lap("sort")
+		chargraph, s.byLength = graphAndFindCandidates(s.components.list, s.components.weights)
+		s.sw.lap("graph")
+		if sf.dups {
+			writeDuplicates(os.Stderr, s.components)
+		}
+	} else {
+		// Whatever was named by neither flag plays both parts, so it's
+		// on both lists.  Counts only matter for components.
+		s.candidates = s.components.clone()
+		if _, err = loadAllTheWords(sf.candidates, s.candidates, opts); err != nil {
+			return nil, err
+		}
+		minLength, err := loadAllTheWords(sf.components, s.components, opts)
+		if err != nil {
+			return nil, err
+		}
+		if minLength < minWordLength {
+			minWordLength = minLength
+		}
+		s.sw.lap("load")
+
+		chargraph = graphOf(s.components.list, s.components.weights)
+		s.readOrder = s.candidates.list
+		s.byLength = findCandidates(s.candidates.list, chargraph)
+		s.sw.lap("graph")
+		if sf.dups {
+			fmt.Fprintln(os.Stderr, "Candidates:")
+			writeDuplicates(os.Stderr, s.candidates)
+			fmt.Fprintln(os.Stderr, "Components:")
+			writeDuplicates(os.Stderr, s.components)
+		}
+	}
+
+	s.dict = dictionary{
+		graph:    chargraph,
+		minLen:   minWordLength,
+		total:    s.components.weights.total(),
+		strategy: sf.strategy,
+	}
+	if err = s.dict.checkStrategy(); err != nil {
+		return nil, err
+	}
+	if s.dict.stop, err = loadWordGraph(sf.stop, opts); err != nil {
+		return nil, err
+	}
+	if s.dict.allow, err = loadWordGraph(sf.allow, opts); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// withSources fills in where each of the results was found.
+func (s *search) withSources(results potentials) potentials {
+	for i := range results {
+		results[i].sources = s.candidates.sources(results[i].whole)
+	}
+	return results
+}
+
+//////////////
+//
+// And now, without any further ado...
+//
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run carries out the command args begin with.  If they don't begin with
+// one, they're taken to be for longest, which is what compound did before
+// it had any other commands.
+func run(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage(nil))
+		return exitUsage
+	}
+	switch args[0] {
+	case "-h", "-help", "--help":
+		fmt.Fprint(os.Stderr, usage(nil))
+		return exitSuccess
+	}
+	if c := findCommand(args[0]); c != nil {
+		return c.run(args[1:])
+	}
+	if c, rest, found := undeprecate(args); found {
+		return c.run(rest)
+	}
+	return findCommand("longest").run(args)
+}
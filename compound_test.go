@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
@@ -31,6 +35,9 @@ var sortedTestWords words
 // A bytegraph populated from the above words.  Generated in init().
 var testGraph bytegraph
 
+// ...and the unweighted dictionary built around it.
+var testDict dictionary
+
 // There's a little bit of a chicken-and-egg going on here.  I'm
 // relying on makegraph, Len, Less, and Swap to all function
 // correctly in order to populate sortedTestWords and testGraph.
@@ -48,15 +55,16 @@ func init() {
 
 	testGraph.next = make(map[byte]bytegraph)
 	for _, w := range sortedTestWords {
-		_ = makegraph(w, &testGraph)
+		_ = makegraph(w, 0, &testGraph)
 	}
+	testDict = dictionary{graph: testGraph, minLen: 2}
 }
 
 func TestLen(t *testing.T) {
 	expected := len(testWords)
 	actual := testWords.Len()
 	if expected != actual {
-		t.Errorf("Len: Expected %q but got %q", expected, actual)
+		t.Errorf("Len: Expected %d but got %d", expected, actual)
 	}
 }
 
@@ -129,7 +137,7 @@ func TestMakegraph(t *testing.T) {
 	testgraph := bytegraph{}
 	testgraph.next = make(map[byte]bytegraph)
 	for _, w := range shortWords {
-		_ = makegraph(w, &testgraph)
+		_ = makegraph(w, 0, &testgraph)
 	}
 
 	if !reflect.DeepEqual(testgraph, shortGraph) {
@@ -168,7 +176,7 @@ func TestSubWords(t *testing.T) {
 	}
 
 	for _, tst := range swTests {
-		actual := subWords(tst.w, testGraph, 2)
+		actual := subWords(tst.w, testDict)
 		if !reflect.DeepEqual(tst.expect, actual) {
 			t.Errorf("subWords - Expected\n\t%q\nBut got\n\t%q", tst.expect, actual)
 		}
@@ -189,7 +197,7 @@ func TestIsCompound(t *testing.T) {
 	}
 
 	for _, tst := range compTests {
-		actual := (&tst.p).isCompound(testGraph, 2)
+		actual := (&tst.p).isCompound(testDict)
 		if actual != tst.expect {
 			t.Errorf("isCompound - %s came back %v / expected %v",
 				string(tst.p.whole), actual, tst.expect)
@@ -197,6 +205,222 @@ func TestIsCompound(t *testing.T) {
 	}
 }
 
+func TestGraphOf(t *testing.T) {
+	// Order shouldn't matter for membership.
+	g := graphOf(words{word("za"), word("abcd"), word("a"), word("z"), word("ab")}, nil)
+	if !reflect.DeepEqual(g, shortGraph) {
+		t.Errorf("graphOf - Expected:\n\t%v\nBut got\n\t%v", shortGraph, g)
+	}
+}
+
+func TestIsComponent(t *testing.T) {
+	stop := graphOf(words{word("art"), word("qu")}, nil)
+	allow := graphOf(words{word("foo"), word("art"), word("bar"), word("fibble")}, nil)
+
+	var icTests = []struct {
+		d      dictionary
+		w      word
+		expect bool
+	}{
+		{testDict, word("art"), true},
+		{testDict, word("fibble"), false},
+		{dictionary{graph: testGraph, stop: &stop}, word("art"), false},
+		{dictionary{graph: testGraph, stop: &stop}, word("artful"), true},
+		{dictionary{graph: testGraph, allow: &allow}, word("foo"), true},
+		{dictionary{graph: testGraph, allow: &allow}, word("quux"), false},
+		{dictionary{graph: testGraph, allow: &allow}, word("fibble"), false},
+		{dictionary{graph: testGraph, stop: &stop, allow: &allow}, word("art"), false},
+	}
+
+	for _, tst := range icTests {
+		if actual := tst.d.isComponent(tst.w); actual != tst.expect {
+			t.Errorf("isComponent - %s came back %v / expected %v", tst.w, actual, tst.expect)
+		}
+	}
+}
+
+// With "art" stopped, the search has to get past the qu + art + splat
+// reading of "quartsplat" and find quart + splat instead.
+func TestIsCompoundStopAllow(t *testing.T) {
+	stop := graphOf(words{word("art")}, nil)
+	allow := graphOf(words{word("qu"), word("art"), word("splat")}, nil)
+
+	var saTests = []struct {
+		d      dictionary
+		expect words
+	}{
+		{testDict, words{word("qu"), word("art"), word("splat")}},
+		{dictionary{graph: testGraph, minLen: 2, stop: &stop}, words{word("quart"), word("splat")}},
+		{dictionary{graph: testGraph, minLen: 2, allow: &allow}, words{word("qu"), word("art"), word("splat")}},
+		{dictionary{graph: testGraph, minLen: 2, stop: &stop, allow: &allow}, nil},
+	}
+
+	for _, tst := range saTests {
+		p := potential{whole: word("quartsplat"), prefixes: words{word("qu"), word("quart")}}
+		actual := (&p).isCompound(tst.d)
+		if actual != (tst.expect != nil) || !reflect.DeepEqual(tst.expect, p.components) {
+			t.Errorf("isCompound - Expected\n\t%q\nBut got\n\t%q", tst.expect, p.components)
+		}
+	}
+
+	// The same goes for the weighted search.
+	d := weightedDict()
+	ful := graphOf(words{word("ful")}, nil)
+	d.stop = &ful
+	actual, _ := bestSplit(word("quartful"), d)
+	if expected := (words{word("qu"), word("artful")}); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("bestSplit - Expected\n\t%q\nBut got\n\t%q", expected, actual)
+	}
+}
+
+// A weighted list where "quartful" can be read either as the common
+// "quart" + "ful" or the rare "qu" + "artful".
+var weightedWords = words{
+	word("artful"), word("ful"), word("qu"), word("quart"), word("squish"),
+}
+var weightedCounts = weights{
+	"artful": 2, "ful": 50, "qu": 1, "quart": 40, "squish": 7,
+}
+
+func weightedDict() dictionary {
+	g := bytegraph{next: make(map[byte]bytegraph)}
+	for _, w := range weightedWords {
+		_ = makegraph(w, weightedCounts[string(w)], &g)
+	}
+	return dictionary{graph: g, minLen: 2, total: weightedCounts.total()}
+}
+
+func TestBestSplit(t *testing.T) {
+	d := weightedDict()
+	var bsTests = []struct {
+		w      word
+		expect words
+	}{
+		{word("quartful"), words{word("quart"), word("ful")}},
+		{word("quartfulsquish"), words{word("quart"), word("ful"), word("squish")}},
+		{word("artfulqu"), words{word("artful"), word("qu")}},
+		{word("quart"), nil},
+		{word("quartfulsquishy"), nil},
+	}
+
+	for _, tst := range bsTests {
+		actual, score := bestSplit(tst.w, d)
+		if !reflect.DeepEqual(tst.expect, actual) {
+			t.Errorf("bestSplit - Expected\n\t%q\nBut got\n\t%q", tst.expect, actual)
+		}
+		if actual != nil && score >= 0 {
+			t.Errorf("bestSplit - %s scored %v; log probabilities should be negative", tst.w, score)
+		}
+	}
+}
+
+func TestIsCompoundWeighted(t *testing.T) {
+	p := potential{whole: word("quartful"), prefixes: words{word("qu"), word("quart")}}
+	if !(&p).isCompound(weightedDict()) {
+		t.Fatalf("isCompound - %s should be compound", p.whole)
+	}
+	expected := words{word("quart"), word("ful")}
+	if !reflect.DeepEqual(expected, p.components) {
+		t.Errorf("isCompound - Expected components\n\t%q\nBut got\n\t%q", expected, p.components)
+	}
+	if !strings.Contains(p.String(), "log P") {
+		t.Errorf("isCompound - Score missing from %q", p.String())
+	}
+}
+
+func TestFewestSplit(t *testing.T) {
+	// Taking the longest prefix first ends up with three pieces here,
+	// where two would do.
+	list := words{word("foob"), word("ar"), word("baz"), word("foo"), word("barbaz")}
+	d := dictionary{graph: graphOf(list, nil), minLen: 2}
+
+	greedy := potential{whole: word("foobarbaz"), prefixes: words{word("foob"), word("foo")}}
+	fewest := greedy
+	d.strategy = strategyGreedy
+	(&greedy).isCompound(d)
+	d.strategy = strategyFewest
+	(&fewest).isCompound(d)
+	if len(greedy.components) != 3 {
+		t.Errorf("isCompound - Expected a greedy split in three, got %q", greedy.components)
+	}
+	if expected := (words{word("foo"), word("barbaz")}); !reflect.DeepEqual(expected, fewest.components) {
+		t.Errorf("isCompound - Expected the fewest components\n\t%q\nBut got\n\t%q", expected, fewest.components)
+	}
+
+	if actual := fewestSplit(word("foob"), d); actual != nil {
+		t.Errorf("fewestSplit - foob is not compound, but got %q", actual)
+	}
+	if actual := fewestSplit(word("quartfulsquish"), weightedDict()); len(actual) != 3 {
+		t.Errorf("fewestSplit - Expected three components, got %q", actual)
+	}
+}
+
+func TestChosenStrategy(t *testing.T) {
+	var csTests = []struct {
+		d      dictionary
+		expect string
+	}{
+		{testDict, strategyGreedy},
+		{weightedDict(), strategyProbable},
+		{dictionary{strategy: strategyFewest, total: 100}, strategyFewest},
+	}
+
+	for _, tst := range csTests {
+		if actual := tst.d.chosenStrategy(); actual != tst.expect {
+			t.Errorf("chosenStrategy - Expected %s but got %s", tst.expect, actual)
+		}
+	}
+}
+
+func TestCheckStrategy(t *testing.T) {
+	var csTests = []struct {
+		strategy string
+		total    int
+		ok       bool
+	}{
+		{"", 0, true},
+		{strategyGreedy, 0, true},
+		{strategyFewest, 0, true},
+		{strategyProbable, 100, true},
+		{strategyProbable, 0, false},
+		{"random", 0, false},
+	}
+
+	for _, tst := range csTests {
+		err := dictionary{strategy: tst.strategy, total: tst.total}.checkStrategy()
+		if (err == nil) != tst.ok {
+			t.Errorf("checkStrategy - %q with total %d gave error %v", tst.strategy, tst.total, err)
+		}
+	}
+}
+
+func TestUnmakegraph(t *testing.T) {
+	g := graphOf(words{word("foo"), word("foobar"), word("fib")}, nil)
+
+	if !unmakegraph(word("foobar"), &g) || isWord(word("foobar"), g) || !isWord(word("foo"), g) {
+		t.Errorf("unmakegraph - foobar should have gone, leaving foo")
+	}
+	// Nothing should be left beyond the end of foo.
+	if n := g.next['f'].next['o'].next['o']; len(n.next) != 0 {
+		t.Errorf("unmakegraph - Left %d branches after foo", len(n.next))
+	}
+	if !unmakegraph(word("foo"), &g) || isWord(word("foo"), g) || !isWord(word("fib"), g) {
+		t.Errorf("unmakegraph - foo should have gone, leaving fib")
+	}
+	if unmakegraph(word("fi"), &g) || unmakegraph(word("bogus"), &g) || !isWord(word("fib"), g) {
+		t.Errorf("unmakegraph - Removed a word that wasn't there")
+	}
+}
+
+func TestWeightsTotal(t *testing.T) {
+	if actual := weightedCounts.total(); actual != 100 {
+		t.Errorf("total - Expected 100 but got %d", actual)
+	}
+	if actual := (weights{}).total(); actual != 0 {
+		t.Errorf("total - Expected 0 for no weights but got %d", actual)
+	}
+}
+
 // NOTE: This only tests whether or not the String() method returns
 // something which contains the original word.  Anything beyond that
 // would just enforce some arbitrary string representation.
@@ -241,8 +465,12 @@ func TestLoadWordsFrom(t *testing.T) {
 		}
 	}
 
-	actualWords := make(words, 0)
-	actualMinLen := loadWordsFrom(source, &actualWords)
+	ws := newWordset()
+	actualMinLen, err := loadWordsFrom(source, "test", ws, noLimit)
+	if err != nil {
+		t.Fatalf("loadWordsFrom - Unexpected error: %v", err)
+	}
+	actualWords := ws.list
 
 	if actualMinLen != testMinLen {
 		t.Errorf("loadWordsFrom - MinLen mismatch: expected: %d, got: %d\n",
@@ -255,8 +483,317 @@ func TestLoadWordsFrom(t *testing.T) {
 	}
 }
 
+func TestLoadWeightedWordsFrom(t *testing.T) {
+	source := strings.NewReader("quart\t40\nqu\t1\nsquish\nquart\t2\n")
+
+	expectedWords := words{word("quart"), word("qu"), word("squish")}
+	expectedCounts := weights{"quart": 42, "qu": 1}
+
+	ws := newWordset()
+	minLen, err := loadWordsFrom(source, "test", ws, noLimit)
+	if err != nil {
+		t.Fatalf("loadWordsFrom - Unexpected error: %v", err)
+	}
+	actualWords, actualCounts := ws.list, ws.weights
+
+	if minLen != 2 {
+		t.Errorf("loadWordsFrom - MinLen mismatch: expected: 2, got: %d\n", minLen)
+	}
+	if !reflect.DeepEqual(expectedWords, actualWords) {
+		t.Errorf("loadWordsFrom - Word list mismatch.\n"+
+			"Expected:\n\t%q\nActual:\n\t%q\n", expectedWords, actualWords)
+	}
+	if !reflect.DeepEqual(expectedCounts, actualCounts) {
+		t.Errorf("loadWordsFrom - Weight mismatch.\n"+
+			"Expected:\n\t%v\nActual:\n\t%v\n", expectedCounts, actualCounts)
+	}
+}
+
+func TestLoadWordsFromBadCount(t *testing.T) {
+	for _, bad := range []string{"quart\tforty\n", "quart\t-40\n", "quart\t\n"} {
+		_, err := loadWordsFrom(strings.NewReader(bad), "test", newWordset(), noLimit)
+		if err == nil || !strings.Contains(err.Error(), "quart") {
+			t.Errorf("loadWordsFrom - %q: expected an error naming the word, got %v", bad, err)
+		}
+	}
+}
+
+// Blank lines, and counts with no word, add no word, so the shortest word
+// can't be the empty one.
+func TestLoadWordsFromBlankLines(t *testing.T) {
+	ws := newWordset()
+	minLen, err := loadWordsFrom(strings.NewReader("foo\n\nbar\n\t12\nfoozbar\n\n"), "test", ws, noLimit)
+	if err != nil {
+		t.Fatalf("loadWordsFrom - Unexpected error: %v", err)
+	}
+	expected := words{word("foo"), word("bar"), word("foozbar")}
+	if !reflect.DeepEqual(expected, ws.list) || minLen != 3 {
+		t.Errorf("loadWordsFrom - Expected %q (shortest 3), got %q (shortest %d)", expected, ws.list, minLen)
+	}
+
+	g := graphOf(ws.list, nil)
+	d := dictionary{graph: g, minLen: minLen}
+	results, _ := checkWords(words{word("foozbar"), word("fooqbar")}, d)
+	if results[0].String() != "foozbar [NOT COMPOUND]" || results[1].String() != "fooqbar [NOT COMPOUND]" {
+		t.Errorf("loadWordsFrom - Got %v", results)
+	}
+}
+
+// Duplicates, whether in the same stream or different ones, go on the list
+// just once, but every place they were found is remembered.
+func TestLoadDuplicates(t *testing.T) {
+	ws := newWordset()
+	_, err := loadWordsFrom(strings.NewReader("foo\nbar\nfoo\n"), "one.list", ws, noLimit)
+	if err == nil {
+		_, err = loadWordsFrom(strings.NewReader("quux\nbar\n"), "two.list", ws, noLimit)
+	}
+	if err != nil {
+		t.Fatalf("loadWordsFrom - Unexpected error: %v", err)
+	}
+
+	expectedWords := words{word("foo"), word("bar"), word("quux")}
+	if !reflect.DeepEqual(expectedWords, ws.list) || ws.read != 5 {
+		t.Errorf("loadWordsFrom - Expected\n\t%q (5 read)\nBut got\n\t%q (%d read)", expectedWords, ws.list, ws.read)
+	}
+
+	expectedOrigins := map[string][]occurrence{
+		"foo":  {{"one.list", 1}, {"one.list", 3}},
+		"bar":  {{"one.list", 2}, {"two.list", 2}},
+		"quux": {{"two.list", 1}},
+	}
+	if !reflect.DeepEqual(expectedOrigins, ws.origins) {
+		t.Errorf("loadWordsFrom - Expected origins\n\t%v\nBut got\n\t%v", expectedOrigins, ws.origins)
+	}
+
+	if expected, actual := []string{"one.list"}, ws.sources(word("foo")); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("sources - Expected %q but got %q", expected, actual)
+	}
+	if expected, actual := []string{"one.list", "two.list"}, ws.sources(word("bar")); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("sources - Expected %q but got %q", expected, actual)
+	}
+	aba := newWordset()
+	for _, file := range []string{"a.txt", "b.txt", "a.txt"} {
+		aba.add(word("foo"), 0, occurrence{file, 1})
+	}
+	if expected, actual := []string{"a.txt", "b.txt"}, aba.sources(word("foo")); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("sources - Expected %q once each, but got %q", expected, actual)
+	}
+
+	var report bytes.Buffer
+	writeDuplicates(&report, ws)
+	expectedReport := "foo\tone.list:1\tone.list:3\nbar\tone.list:2\ttwo.list:2\n"
+	if report.String() != expectedReport {
+		t.Errorf("writeDuplicates - Expected\n%s\nBut got\n%s", expectedReport, report.String())
+	}
+
+	// The whole point: a duplicate must not turn up as its own prefix.
+	sort.Sort(ws.list)
+	_, pm := graphAndFindCandidates(ws.list, ws.weights)
+	if len(pm) != 0 {
+		t.Errorf("graphAndFindCandidates - Expected no candidates, got %v", pm)
+	}
+}
+
+func TestWordsetRemove(t *testing.T) {
+	ws := newWordset()
+	ws.add(word("foo"), 3, occurrence{"one.list", 1})
+	ws.add(word("bar"), 0, occurrence{"one.list", 2})
+
+	if !ws.remove(word("foo")) || ws.remove(word("foo")) {
+		t.Errorf("wordset.remove - foo should be removed exactly once")
+	}
+	if !reflect.DeepEqual(ws.list, words{word("bar")}) || ws.weights["foo"] != 0 || ws.sources(word("foo")) != nil {
+		t.Errorf("wordset.remove - foo is still around: %q %v", ws.list, ws.weights)
+	}
+}
+
+func TestWordsetClone(t *testing.T) {
+	ws := newWordset()
+	ws.add(word("foo"), 3, occurrence{"one.list", 1})
+	c := ws.clone()
+	c.add(word("foo"), 2, occurrence{"two.list", 1})
+	c.add(word("bar"), 0, occurrence{"two.list", 2})
+
+	if len(ws.list) != 1 || ws.weights["foo"] != 3 || len(ws.origins["foo"]) != 1 || ws.read != 1 {
+		t.Errorf("clone - Adding to the clone changed the original: %+v", ws)
+	}
+	if len(c.list) != 2 || c.weights["foo"] != 5 || len(c.origins["foo"]) != 2 || c.read != 3 {
+		t.Errorf("clone - Clone didn't take additions: %+v", c)
+	}
+}
+
+// The default: lines of any length, and no policy needed to deal with
+// the ones that are too long.
+var noLimit = lineLimit{policy: oversizeFail}
+
+func TestLineLimitValid(t *testing.T) {
+	var llTests = []struct {
+		lim    lineLimit
+		expect bool
+	}{
+		{noLimit, true},
+		{lineLimit{max: 10, policy: oversizeSkip}, true},
+		{lineLimit{max: 10, policy: oversizeTruncate}, true},
+		{lineLimit{max: -1, policy: oversizeFail}, false},
+		{lineLimit{max: 10, policy: "ignore"}, false},
+	}
+	for _, tst := range llTests {
+		if actual := tst.lim.valid(); actual != tst.expect {
+			t.Errorf("valid - %+v came back %v / expected %v", tst.lim, actual, tst.expect)
+		}
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	// Well past both bufio's default buffer and bufio.Scanner's 64KiB limit.
+	long := strings.Repeat("x", 100000)
+	input := "foo\r\n" + long + "\nbar"
+
+	var rlTests = []struct {
+		max    int
+		expect []string
+	}{
+		{0, []string{"foo", long, "bar"}},
+		{5, []string{"foo", "xxxxx", "bar"}},
+	}
+	for _, tst := range rlTests {
+		r := bufio.NewReader(strings.NewReader(input))
+		for i, expected := range tst.expect {
+			line, size, err := readLine(r, tst.max)
+			if err != nil || string(line) != expected {
+				t.Errorf("readLine - max %d, line %d: got %d bytes / error %v", tst.max, i+1, len(line), err)
+			}
+			if i == 1 && size != len(long) {
+				t.Errorf("readLine - max %d: size of long line should be %d, got %d", tst.max, len(long), size)
+			}
+		}
+		if _, _, err := readLine(r, tst.max); err != io.EOF {
+			t.Errorf("readLine - max %d: expected io.EOF at the end, got %v", tst.max, err)
+		}
+	}
+}
+
+func TestLoadWordsFromOversize(t *testing.T) {
+	input := "foo\nsplatter\nqu\n"
+
+	var osTests = []struct {
+		lim    lineLimit
+		expect words
+		errs   bool
+	}{
+		{noLimit, words{word("foo"), word("splatter"), word("qu")}, false},
+		{lineLimit{max: 8, policy: oversizeFail}, words{word("foo"), word("splatter"), word("qu")}, false},
+		{lineLimit{max: 5, policy: oversizeSkip}, words{word("foo"), word("qu")}, false},
+		{lineLimit{max: 5, policy: oversizeTruncate}, words{word("foo"), word("splat"), word("qu")}, false},
+		{lineLimit{max: 5, policy: oversizeFail}, words{word("foo")}, true},
+	}
+
+	for _, tst := range osTests {
+		ws := newWordset()
+		_, err := loadWordsFrom(strings.NewReader(input), "test", ws, tst.lim)
+		actualWords := ws.list
+		if !reflect.DeepEqual(tst.expect, actualWords) {
+			t.Errorf("loadWordsFrom - %+v: Expected\n\t%q\nBut got\n\t%q", tst.lim, tst.expect, actualWords)
+		}
+		if tst.errs && (err == nil || !strings.Contains(err.Error(), "line 2")) {
+			t.Errorf("loadWordsFrom - %+v: expected an error naming line 2, got %v", tst.lim, err)
+		} else if !tst.errs && err != nil {
+			t.Errorf("loadWordsFrom - %+v: unexpected error %v", tst.lim, err)
+		}
+	}
+}
+
+func TestLoadAllTheWords(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.list")
+	bad := filepath.Join(dir, "bad.list")
+	missing := filepath.Join(dir, "missing.list")
+	if err := os.WriteFile(good, []byte("foo\nquux\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bad, []byte("foo\tlots\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ws := newWordset()
+	minLen, err := loadAllTheWords([]string{good}, ws, loadOptions{lineLimit: noLimit})
+	if err != nil || minLen != 3 || len(ws.list) != 2 {
+		t.Errorf("loadAllTheWords - Got %q, minLen %d, error %v", ws.list, minLen, err)
+	}
+
+	// Asked to, it should say how many words came from each file.
+	var progress bytes.Buffer
+	_, err = loadAllTheWords([]string{good, good}, newWordset(),
+		loadOptions{lineLimit: noLimit, progress: &progress})
+	if expected := good + ": 2 words, 2 new\n" + good + ": 2 words, 0 new\n"; err != nil || progress.String() != expected {
+		t.Errorf("loadAllTheWords - Expected progress\n\t%q\nBut got\n\t%q (error %v)", expected, progress.String(), err)
+	}
+
+	// Errors must say which file they came from.
+	for _, file := range []string{missing, bad} {
+		_, err = loadAllTheWords([]string{good, file}, newWordset(), loadOptions{lineLimit: noLimit})
+		if err == nil || !strings.Contains(err.Error(), file) {
+			t.Errorf("loadAllTheWords - Expected an error naming %s, got %v", file, err)
+		}
+	}
+}
+
+// writeTree creates each of the named files (and the directories they're
+// in) under dir, each holding a single word.
+func writeTree(t *testing.T, dir string, names ...string) {
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("foo\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExpandFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, "a.txt", "b.dic", "sub/c.txt", "sub/deep/d.txt", "skip/e.txt")
+	in := func(names ...string) (paths []string) {
+		for _, name := range names {
+			paths = append(paths, filepath.Join(dir, filepath.FromSlash(name)))
+		}
+		return
+	}
+
+	var efTests = []struct {
+		args   []string
+		opts   loadOptions
+		expect []string
+	}{
+		{[]string{"-"}, loadOptions{}, []string{"-"}},
+		{in("b.dic"), loadOptions{}, in("b.dic")},
+		{in("*.txt"), loadOptions{}, in("a.txt")},
+		{in("sub"), loadOptions{}, in("sub/c.txt", "sub/deep/d.txt")},
+		{[]string{dir}, loadOptions{include: fileList{"*.txt"}, exclude: fileList{"skip"}},
+			in("a.txt", "sub/c.txt", "sub/deep/d.txt")},
+		{[]string{dir}, loadOptions{exclude: fileList{"deep", "*.dic"}},
+			in("a.txt", "skip/e.txt", "sub/c.txt")},
+		{in("s*"), loadOptions{include: fileList{"e.*"}}, in("skip/e.txt")},
+		// Names that don't exist are left for loadAllTheWords to complain about.
+		{in("missing.txt"), loadOptions{}, in("missing.txt")},
+	}
+
+	for _, tst := range efTests {
+		actual, err := expandFiles(tst.args, tst.opts)
+		if err != nil || !reflect.DeepEqual(tst.expect, actual) {
+			t.Errorf("expandFiles - %q: Expected\n\t%q\nBut got\n\t%q (error %v)", tst.args, tst.expect, actual, err)
+		}
+	}
+
+	if _, err := expandFiles(in("*.none"), loadOptions{}); err == nil {
+		t.Errorf("expandFiles - A pattern matching nothing should be an error")
+	}
+}
+
 func TestGraphAndFindCandidates(t *testing.T) {
-	actualGraph, actualCandidatesByLength := graphAndFindCandidates(shortWords)
+	actualGraph, actualCandidatesByLength := graphAndFindCandidates(shortWords, weights{})
 
 	if !reflect.DeepEqual(shortGraph, actualGraph) {
 		t.Errorf("graphAndFindCandidates - bytegraph mismatch.\n"+
@@ -270,12 +807,183 @@ func TestGraphAndFindCandidates(t *testing.T) {
 
 }
 
+func TestLongestCompounds(t *testing.T) {
+	_, pm := graphAndFindCandidates(sortedTestWords, nil)
+
+	var lcTests = []struct {
+		n      int
+		expect words
+	}{
+		{1, words{word("barfooquux")}},
+		{2, words{word("barfooquux"), word("foobar")}},
+		{0, words{word("barfooquux"), word("foobar"), word("quart")}},
+		{10, words{word("barfooquux"), word("foobar"), word("quart")}},
+	}
+
+	for _, tst := range lcTests {
+		var actual words
+		for _, p := range longestCompounds(pm, testDict, tst.n) {
+			actual = append(actual, p.whole)
+		}
+		if !reflect.DeepEqual(tst.expect, actual) {
+			t.Errorf("longestCompounds - %d: Expected\n\t%q\nBut got\n\t%q", tst.n, tst.expect, actual)
+		}
+	}
+}
+
+func TestPrefixesOf(t *testing.T) {
+	var poTests = []struct {
+		w      word
+		expect words
+	}{
+		{word("abcde"), words{word("abcd"), word("ab"), word("a")}},
+		{word("abcd"), words{word("ab"), word("a")}},
+		{word("zap"), words{word("za"), word("z")}},
+		{word("a"), nil},
+		{word("bcd"), nil},
+	}
+
+	for _, tst := range poTests {
+		actual := prefixesOf(tst.w, shortGraph)
+		if !reflect.DeepEqual(tst.expect, actual) {
+			t.Errorf("prefixesOf - %s: Expected\n\t%q\nBut got\n\t%q", tst.w, tst.expect, actual)
+		}
+	}
+}
+
+// Neither "abz" nor "zab" is in shortWords, but both begin with one.
+func TestFindCandidates(t *testing.T) {
+	candidates := words{word("abz"), word("zab"), word("abcd"), word("bza")}
+	expected := map[int]potentials{
+		3: {
+			{whole: word("abz"), prefixes: words{word("ab"), word("a")}},
+			{whole: word("zab"), prefixes: words{word("za"), word("z")}},
+		},
+		4: {{whole: word("abcd"), prefixes: words{word("ab"), word("a")}}},
+	}
+
+	actual := findCandidates(candidates, shortGraph)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("findCandidates - Expected:\n\t%#v\nActual\n\t%#v\n", expected, actual)
+	}
+}
+
+func TestFileList(t *testing.T) {
+	var fl fileList
+	_ = fl.Set("a.list")
+	_ = fl.Set("b.list")
+	if !reflect.DeepEqual(fl, fileList{"a.list", "b.list"}) || fl.String() != "a.list,b.list" {
+		t.Errorf("fileList - Got %q / %q", []string(fl), fl.String())
+	}
+}
+
+func TestSearchFlagsFinish(t *testing.T) {
+	sf := searchFlags{dictionaryFlags: dictionaryFlags{load: loadOptions{lineLimit: noLimit}}}
+	if err := sf.finish(nil); err == nil {
+		t.Errorf("searchFlags.finish - Expected an error with no word lists")
+	}
+	if err := sf.finish([]string{"word.list"}); err != nil {
+		t.Errorf("searchFlags.finish - Unexpected error %v", err)
+	}
+	sf.candidates, sf.components = fileList{"a.list"}, fileList{"b.list"}
+	if err := sf.finish(nil); err != nil {
+		t.Errorf("searchFlags.finish - Unexpected error %v with candidates and components", err)
+	}
+	sf.strategy = "silly"
+	if err := sf.finish(nil); err == nil {
+		t.Errorf("searchFlags.finish - Expected an error for a silly strategy")
+	}
+}
+
+func TestLoadSearch(t *testing.T) {
+	dir := t.TempDir()
+	both := filepath.Join(dir, "both.list")
+	products := filepath.Join(dir, "products.list")
+	english := filepath.Join(dir, "english.list")
+	for file, content := range map[string]string{
+		both:     "foo\nbar\nfoobar\nfooqux\n",
+		products: "barfoo\nquuxfoo\n",
+		english:  "bar\n",
+	} {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sf := searchFlags{dictionaryFlags: dictionaryFlags{load: loadOptions{lineLimit: noLimit}}}
+
+	// fooqux begins with a word, so it's a candidate, but it doesn't end
+	// with one, so the search passes over it.
+	s, err := loadSearch([]string{both}, sf)
+	if err != nil || len(s.byLength[6]) != 2 || s.candidates != s.components {
+		t.Fatalf("loadSearch - Got %+v (error %v)", s, err)
+	}
+	if found := longestCompounds(s.byLength, s.dict, 0); len(found) != 1 || string(found[0].whole) != "foobar" {
+		t.Errorf("longestCompounds - Expected only foobar, got %v", found)
+	}
+
+	// barfoo is made of one word from each list, and quuxfoo isn't made
+	// of words at all.
+	sf.candidates, sf.components = fileList{products}, fileList{english}
+	if s, err = loadSearch([]string{both}, sf); err != nil {
+		t.Fatal(err)
+	}
+	results := s.withSources(longestCompounds(s.byLength, s.dict, 0))
+	if len(results) != 2 || results[1].String() != "barfoo = bar + foo" || results[1].sources[0] != products {
+		t.Errorf("loadSearch - Got %v", results)
+	}
+	if isWord(word("barfoo"), s.dict.graph) {
+		t.Errorf("loadSearch - A candidate made it into the dictionary")
+	}
+
+	sf.strategy = strategyProbable
+	if _, err = loadSearch([]string{both}, sf); err != errNeedsCounts {
+		t.Errorf("loadSearch - Expected %v, got %v", errNeedsCounts, err)
+	}
+}
+
 // Ok, not the most robust of tests, but there's really not a lot that can
 // be done on this one.
 func TestUsage(t *testing.T) {
-	usageMessage := usage()
+	usageMessage := usage(nil)
 
 	if !strings.Contains(usageMessage, "Usage") {
 		t.Errorf("usage - Does not contain \"Usage\"\n")
 	}
 }
+
+func TestEndsInWord(t *testing.T) {
+	var eTests = []struct {
+		w        word
+		expected bool
+	}{
+		{word("quartsplat"), true},
+		{word("foobary"), false},
+		{word("splatfoo"), true},
+		{word("bar"), false}, // Only itself.
+		{word("xbar"), true},
+	}
+	for _, et := range eTests {
+		if actual := endsInWord(et.w, testGraph); actual != et.expected {
+			t.Errorf("endsInWord - For %s, expected %v, got %v", et.w, et.expected, actual)
+		}
+	}
+}
+
+func TestWordsWithPrefix(t *testing.T) {
+	var wTests = []struct {
+		prefix   word
+		limit    int
+		expected words
+	}{
+		{word("qu"), 0, words{word("qu"), word("quart"), word("quux")}},
+		{word("qu"), 2, words{word("qu"), word("quart")}},
+		{word("s"), 0, words{word("splat"), word("splatter"), word("squish")}},
+		{word(""), 2, words{word("art"), word("artful")}},
+		{word("xyz"), 0, nil},
+	}
+	for _, wt := range wTests {
+		if actual := testGraph.WordsWithPrefix(wt.prefix, wt.limit); !reflect.DeepEqual(actual, wt.expected) {
+			t.Errorf("WordsWithPrefix - For %q and %d, expected %q, got %q", wt.prefix, wt.limit, wt.expected, actual)
+		}
+	}
+}
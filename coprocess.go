@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// A coprocessRequest is one line of input in -coprocess mode, such as
+// {"id": 7, "op": "decompose", "word": "backyard"}.  The id can be any
+// JSON value at all; it's handed back untouched with the response, so
+// that the caller can tell which response goes with which request.
+type coprocessRequest struct {
+	ID   json.RawMessage `json:"id"`
+	Op   string          `json:"op"`
+	Word string          `json:"word"`
+}
+
+// A coprocessResponse is one line of output in -coprocess mode.  Its
+// result depends on the op: the word's record for decompose, whether it's
+// compound for check, and the words it begins with for prefix.
+type coprocessResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result interface{}     `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// coprocessOps are the operations a coprocess request may ask for.
+var coprocessOps = map[string]func(w word, d dictionary, ws *wordset) interface{}{
+	"decompose": func(w word, d dictionary, ws *wordset) interface{} {
+		results, _ := checkWords(words{w}, d)
+		results[0].sources = ws.sources(w)
+		return results[0].record()
+	},
+	"check": func(w word, d dictionary, ws *wordset) interface{} {
+		_, compound := checkWords(words{w}, d)
+		return compound
+	},
+	"prefix": func(w word, d dictionary, ws *wordset) interface{} {
+		pfxs := make([]string, 0)
+		for _, pfx := range prefixesOf(w, d.graph) {
+			pfxs = append(pfxs, string(pfx))
+		}
+		return pfxs
+	},
+}
+
+// answer works out the response to a single line of input.
+func answer(line []byte, d dictionary, ws *wordset) (resp coprocessResponse) {
+	var req coprocessRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		resp.Error = fmt.Sprintf("bad request: %v", err)
+		return
+	}
+	resp.ID = req.ID
+	op, known := coprocessOps[req.Op]
+	switch {
+	case !known:
+		resp.Error = fmt.Sprintf("unknown op %q", req.Op)
+	case req.Word == "":
+		resp.Error = "no word given"
+	default:
+		resp.Result = op(word(req.Word), d, ws)
+	}
+	return
+}
+
+// coprocess reads requests from in, one JSON object per line, and writes
+// a response to each to out, one JSON object per line, until in runs
+// out.  Each response is flushed as soon as it's written, since whoever
+// is on the other end is waiting for it before they send the next.
+func coprocess(in io.Reader, out io.Writer, d dictionary, ws *wordset) error {
+	r := bufio.NewReader(in)
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	for {
+		line, err := r.ReadBytes('\n')
+		if strings.TrimSpace(string(line)) != "" {
+			if encErr := enc.Encode(answer(line, d, ws)); encErr != nil {
+				return encErr
+			}
+			if flushErr := w.Flush(); flushErr != nil {
+				return flushErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// coprocessFlags are the flags of the coprocess command, which loads the
+// word lists, then answers requests about them on STDIN until it's closed.
+func coprocessFlags(fs *flag.FlagSet) action {
+	var sf searchFlags
+	sf.register(fs)
+
+	return func(fs *flag.FlagSet) int {
+		// STDIN can't be both the word list and the requests.
+		for _, arg := range append(fs.Args(), append(sf.candidates, sf.components...)...) {
+			if arg == "-" {
+				return misuse(fs, errors.New("the word list can't come from STDIN as well as the requests"))
+			}
+		}
+		s, status := setUpSearch(fs, sf, fs.Args())
+		if s == nil {
+			return status
+		}
+		if err := coprocess(os.Stdin, os.Stdout, s.dict, s.components); err != nil {
+			complain(err)
+			return exitIO
+		}
+		return exitSuccess
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestAnswer(t *testing.T) {
+	var aTests = []struct {
+		line   string
+		expect string
+	}{
+		{`{"id": 1, "op": "check", "word": "quartsplat"}`, `{"id":1,"result":true}`},
+		{`{"id": "x", "op": "check", "word": "fibble"}`, `{"id":"x","result":false}`},
+		{`{"id": 2, "op": "prefix", "word": "quartful"}`, `{"id":2,"result":["quart","qu"]}`},
+		{`{"id": 3, "op": "prefix", "word": "bogus"}`, `{"id":3,"result":[]}`},
+		{`{"id": 4, "op": "decompose", "word": "foobar"}`,
+			`{"id":4,"result":{"word":"foobar","bytes":6,"runes":6,"compound":true,` +
+				`"components":[{"word":"foo","offset":0},{"word":"bar","offset":3}]}}`},
+		{`{"op": "check", "word": "foobar"}`, `{"id":null,"result":true}`},
+		{`{"id": 5, "op": "split", "word": "foobar"}`, `{"id":5,"error":"unknown op \"split\""}`},
+		{`{"id": 6, "op": "check"}`, `{"id":6,"error":"no word given"}`},
+	}
+
+	for _, tst := range aTests {
+		var out bytes.Buffer
+		if err := coprocess(strings.NewReader(tst.line), &out, testDict, newWordset()); err != nil {
+			t.Errorf("coprocess - %s: %v", tst.line, err)
+		}
+		if actual := strings.TrimSpace(out.String()); actual != tst.expect {
+			t.Errorf("coprocess - %s: Expected\n\t%s\nBut got\n\t%s", tst.line, tst.expect, actual)
+		}
+	}
+
+	if resp := answer([]byte("not json"), testDict, newWordset()); !strings.HasPrefix(resp.Error, "bad request") {
+		t.Errorf("answer - Expected a bad request, got %+v", resp)
+	}
+}
+
+// A flushRecorder keeps track of each write it gets.
+type flushRecorder struct {
+	writes []string
+}
+
+func (fr *flushRecorder) Write(p []byte) (int, error) {
+	fr.writes = append(fr.writes, string(p))
+	return len(p), nil
+}
+
+func TestCoprocess(t *testing.T) {
+	// Blank lines are ignored, and each response must be written on its
+	// own, as soon as it's ready.
+	in := "{\"id\": 1, \"op\": \"check\", \"word\": \"foobar\"}\n\n" +
+		"{\"id\": 2, \"op\": \"check\", \"word\": \"fibble\"}\n"
+	var fr flushRecorder
+	if err := coprocess(strings.NewReader(in), &fr, testDict, newWordset()); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"{\"id\":1,\"result\":true}\n", "{\"id\":2,\"result\":false}\n"}
+	if strings.Join(fr.writes, "|") != strings.Join(expected, "|") {
+		t.Errorf("coprocess - Expected writes %q, got %q", expected, fr.writes)
+	}
+
+	// It carries on until the input runs out, and no further.
+	r, w := io.Pipe()
+	done := make(chan error)
+	var out bytes.Buffer
+	go func() { done <- coprocess(r, &out, testDict, newWordset()) }()
+	_, _ = io.WriteString(w, "{\"id\": 1, \"op\": \"check\", \"word\": \"foobar\"}\n")
+	w.Close()
+	if err := <-done; err != nil || !strings.Contains(out.String(), `"result":true`) {
+		t.Errorf("coprocess - Got %q (error %v)", out.String(), err)
+	}
+}
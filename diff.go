@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A compoundChange is a compound word which is only compound in one of
+// two vocabularies, or which is split differently in each.  Old or New
+// is empty where the word isn't compound at all.
+type compoundChange struct {
+	Word string   `json:"word"`
+	Old  []string `json:"old,omitempty"`
+	New  []string `json:"new,omitempty"`
+}
+
+// A countChange is a length at which two vocabularies have a different
+// number of compound words.
+type countChange struct {
+	Length int `json:"length"`
+	Old    int `json:"old"`
+	New    int `json:"new"`
+}
+
+// A splitWord is a compound word and the components it's made of.
+type splitWord struct {
+	Word       string   `json:"word"`
+	Components []string `json:"components"`
+}
+
+// A longestChange is the longest compound word in each of two
+// vocabularies; either is nil if a vocabulary has no compound words.
+type longestChange struct {
+	Old *splitWord `json:"old"`
+	New *splitWord `json:"new"`
+}
+
+// A compoundDiff is everything that changed about the compound words
+// going from an old vocabulary to a new one.  Each list of changes is
+// longest first, and Counts holds only the lengths at which the number
+// of compound words changed.
+type compoundDiff struct {
+	Appeared    []compoundChange `json:"appeared"`
+	Disappeared []compoundChange `json:"disappeared"`
+	Changed     []compoundChange `json:"changed"`
+	Longest     longestChange    `json:"longest"`
+	Counts      []countChange    `json:"counts"`
+}
+
+func componentNames(p potential) []string {
+	names := make([]string, 0, len(p.components))
+	for _, c := range p.components {
+		names = append(names, string(c))
+	}
+	return names
+}
+
+// diffCompounds compares the compound words of two vocabularies, each as
+// it comes from longestCompounds: longest first.  The changes come out
+// in the same order.
+func diffCompounds(before, after potentials) (cd compoundDiff) {
+	cd = compoundDiff{Appeared: []compoundChange{}, Disappeared: []compoundChange{},
+		Changed: []compoundChange{}, Counts: []countChange{}}
+
+	wasCompound := make(map[string][]string, len(before))
+	counts := make(map[int]*countChange)
+	count := func(length int) *countChange {
+		if counts[length] == nil {
+			counts[length] = &countChange{Length: length}
+		}
+		return counts[length]
+	}
+	for _, p := range before {
+		wasCompound[string(p.whole)] = componentNames(p)
+		count(len(p.whole)).Old++
+	}
+	isCompound := make(map[string]bool, len(after))
+	for _, p := range after {
+		w, parts := string(p.whole), componentNames(p)
+		isCompound[w] = true
+		count(len(p.whole)).New++
+		if was, ok := wasCompound[w]; !ok {
+			cd.Appeared = append(cd.Appeared, compoundChange{Word: w, New: parts})
+		} else if strings.Join(was, "+") != strings.Join(parts, "+") {
+			cd.Changed = append(cd.Changed, compoundChange{Word: w, Old: was, New: parts})
+		}
+	}
+	for _, p := range before {
+		if w := string(p.whole); !isCompound[w] {
+			cd.Disappeared = append(cd.Disappeared, compoundChange{Word: w, Old: wasCompound[w]})
+		}
+	}
+
+	if len(before) > 0 {
+		cd.Longest.Old = &splitWord{string(before[0].whole), componentNames(before[0])}
+	}
+	if len(after) > 0 {
+		cd.Longest.New = &splitWord{string(after[0].whole), componentNames(after[0])}
+	}
+	for _, c := range counts {
+		if c.Old != c.New {
+			cd.Counts = append(cd.Counts, *c)
+		}
+	}
+	sort.Slice(cd.Counts, func(i, j int) bool { return cd.Counts[i].Length < cd.Counts[j].Length })
+	return
+}
+
+// joinComponents writes a word's components the way potential.String does.
+func joinComponents(parts []string) string {
+	return strings.Join(parts, " + ")
+}
+
+func (sw *splitWord) String() string {
+	if sw == nil {
+		return "none"
+	}
+	return sw.Word + " = " + joinComponents(sw.Components)
+}
+
+// writeDiff writes cd as text, a line for each change: + for a compound
+// word which appeared, - for one which disappeared, and ~ for one which
+// is split differently.
+func writeDiff(out io.Writer, cd compoundDiff) error {
+	var b strings.Builder
+	for _, c := range cd.Appeared {
+		fmt.Fprintf(&b, "+ %s = %s\n", c.Word, joinComponents(c.New))
+	}
+	for _, c := range cd.Disappeared {
+		fmt.Fprintf(&b, "- %s = %s\n", c.Word, joinComponents(c.Old))
+	}
+	for _, c := range cd.Changed {
+		fmt.Fprintf(&b, "~ %s = %s (was %s)\n", c.Word, joinComponents(c.New), joinComponents(c.Old))
+	}
+	was, now := cd.Longest.Old.String(), cd.Longest.New.String()
+	if was == now {
+		fmt.Fprintf(&b, "Longest: %s, as before\n", now)
+	} else {
+		fmt.Fprintf(&b, "Longest: %s, was %s\n", now, was)
+	}
+	for _, c := range cd.Counts {
+		fmt.Fprintf(&b, "Compound words of length %d: %d, was %d (%+d)\n", c.Length, c.New, c.Old, c.New-c.Old)
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func writeDiffJSON(out io.Writer, cd compoundDiff) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cd)
+}
+
+// allCompounds loads the word lists named by files, and finds every
+// compound word on them, longest first.
+func allCompounds(files []string, df dictionaryFlags) (potentials, error) {
+	s, err := loadSearch(files, searchFlags{dictionaryFlags: df})
+	if err != nil {
+		return nil, err
+	}
+	return longestCompounds(s.byLength, s.dict, 0), nil
+}
+
+// diffFlags are the flags of the diff command, which compares the
+// compound words of the lists named by -old with those named by -new.
+func diffFlags(fs *flag.FlagSet) action {
+	var oldFiles, newFiles fileList
+	fs.Var(&oldFiles, "old", "word `file` to compare from")
+	fs.Var(&newFiles, "new", "word `file` to compare to")
+	asJSON := fs.Bool("json", false, "write the differences as JSON")
+	var df dictionaryFlags
+	df.register(fs)
+
+	return func(fs *flag.FlagSet) int {
+		if len(oldFiles) == 0 || len(newFiles) == 0 {
+			return misuse(fs, errors.New("word lists are needed for both -old and -new"))
+		}
+		if fs.NArg() > 0 {
+			return misuse(fs, fmt.Errorf("unexpected %q", fs.Arg(0)))
+		}
+		if err := df.finish(); err != nil {
+			return misuse(fs, err)
+		}
+
+		before, err := allCompounds(oldFiles, df)
+		if err != nil {
+			return cantLoad(fs, err)
+		}
+		after, err := allCompounds(newFiles, df)
+		if err != nil {
+			return cantLoad(fs, err)
+		}
+		write := writeDiff
+		if *asJSON {
+			write = writeDiffJSON
+		}
+		if err = write(os.Stdout, diffCompounds(before, after)); err != nil {
+			complain(err)
+			return exitIO
+		}
+		return exitSuccess
+	}
+}
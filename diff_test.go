@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// compoundOf makes the potential for a compound word out of its components.
+func compoundOf(parts ...string) (p potential) {
+	for _, part := range parts {
+		p.whole = append(p.whole, part...)
+		p.components = append(p.components, word(part))
+	}
+	return
+}
+
+var (
+	diffBefore = potentials{compoundOf("quart", "ful"), compoundOf("baz", "bar"), compoundOf("foo", "bar")}
+	diffAfter  = potentials{compoundOf("bar", "foo", "quux"), compoundOf("qu", "artful"), compoundOf("foo", "bar")}
+)
+
+func TestDiffCompounds(t *testing.T) {
+	cd := diffCompounds(diffBefore, diffAfter)
+	expected := compoundDiff{
+		Appeared:    []compoundChange{{Word: "barfooquux", New: []string{"bar", "foo", "quux"}}},
+		Disappeared: []compoundChange{{Word: "bazbar", Old: []string{"baz", "bar"}}},
+		Changed:     []compoundChange{{Word: "quartful", Old: []string{"quart", "ful"}, New: []string{"qu", "artful"}}},
+		Longest: longestChange{
+			Old: &splitWord{"quartful", []string{"quart", "ful"}},
+			New: &splitWord{"barfooquux", []string{"bar", "foo", "quux"}},
+		},
+		Counts: []countChange{{Length: 6, Old: 2, New: 1}, {Length: 10, Old: 0, New: 1}},
+	}
+	if !reflect.DeepEqual(cd, expected) {
+		t.Errorf("diffCompounds - Expected %+v, got %+v", expected, cd)
+	}
+
+	cd = diffCompounds(diffBefore, diffBefore)
+	if len(cd.Appeared)+len(cd.Disappeared)+len(cd.Changed)+len(cd.Counts) != 0 ||
+		cd.Longest.Old.String() != cd.Longest.New.String() {
+		t.Errorf("diffCompounds - Expected no changes, got %+v", cd)
+	}
+	if cd = diffCompounds(nil, nil); cd.Longest.Old != nil || cd.Longest.New.String() != "none" {
+		t.Errorf("diffCompounds - Expected no longest, got %+v", cd.Longest)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeDiff(&b, diffCompounds(diffBefore, diffAfter)); err != nil {
+		t.Fatal(err)
+	}
+	expected := "+ barfooquux = bar + foo + quux\n" +
+		"- bazbar = baz + bar\n" +
+		"~ quartful = qu + artful (was quart + ful)\n" +
+		"Longest: barfooquux = bar + foo + quux, was quartful = quart + ful\n" +
+		"Compound words of length 6: 1, was 2 (-1)\n" +
+		"Compound words of length 10: 1, was 0 (+1)\n"
+	if b.String() != expected {
+		t.Errorf("writeDiff - Expected:\n%s\nGot:\n%s", expected, b.String())
+	}
+
+	b.Reset()
+	if err := writeDiffJSON(&b, diffCompounds(diffBefore, diffBefore)); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if appeared, ok := decoded["appeared"].([]interface{}); !ok || len(appeared) != 0 {
+		t.Errorf("writeDiffJSON - Expected an empty appeared list, got %s", b.String())
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// A span is a stretch of a word, from byte offset from up to (but not
+// including) byte offset to, which is itself a word.
+type span struct {
+	from, to int
+}
+
+// lattice finds every span of w which could be used as a component - all
+// the pieces any search through w might try, whether or not they lead
+// anywhere.  w itself is left out.  Spans come out ordered by where they
+// begin, then by where they end.
+func lattice(w word, d dictionary) (spans []span) {
+	for j := 0; j < len(w); j++ {
+		g := d.graph
+		for i := j; i < len(w); i++ {
+			next, exists := g.next[w[i]]
+			if !exists {
+				break
+			}
+			g = next
+			if g.endOfWord && !(j == 0 && i == len(w)-1) && d.isComponent(w[j:i+1]) {
+				spans = append(spans, span{j, i + 1})
+			}
+		}
+	}
+	return
+}
+
+// writeDOT draws the segmentation lattice of p as a Graphviz digraph.
+// Every byte position in the word is a node, every span which is a word
+// is an edge, and any path from the first node to the last is a way to
+// split the word.  If p has been found to be compound, the path it was
+// split along is highlighted.  Render it with "dot -Tsvg".
+func writeDOT(out io.Writer, p potential, d dictionary) error {
+	chosen := make(map[span]bool)
+	offset := 0
+	for _, c := range p.components {
+		chosen[span{offset, offset + len(c)}] = true
+		offset += len(c)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(string(p.whole)))
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=circle];\n")
+	fmt.Fprintf(&b, "\t%d [shape=doublecircle];\n", len(p.whole))
+	for i := 0; i < len(p.whole); i++ {
+		fmt.Fprintf(&b, "\t%d;\n", i)
+	}
+	for _, s := range lattice(p.whole, d) {
+		fmt.Fprintf(&b, "\t%d -> %d [label=%s", s.from, s.to, dotQuote(string(p.whole[s.from:s.to])))
+		if chosen[s] {
+			b.WriteString(", color=red, fontcolor=red, penwidth=2")
+		}
+		b.WriteString("];\n")
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// dotQuote makes s safe to use as a DOT identifier or label.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// dotFlags are the flags of the dot command, which takes the word to draw,
+// and then the word lists to draw it with.
+func dotFlags(fs *flag.FlagSet) action {
+	var sf searchFlags
+	sf.register(fs)
+
+	return func(fs *flag.FlagSet) int {
+		if fs.NArg() == 0 {
+			return misuse(fs, errors.New("no word to draw"))
+		}
+		s, status := setUpSearch(fs, sf, fs.Args()[1:])
+		if s == nil {
+			return status
+		}
+		w := word(fs.Arg(0))
+		p := potential{whole: w, prefixes: prefixesOf(w, s.dict.graph)}
+		compound := (&p).isCompound(s.dict)
+		if err := writeDOT(os.Stdout, p, s.dict); err != nil {
+			complain(err)
+			return exitIO
+		}
+		if !compound {
+			return exitNoCompound
+		}
+		return exitSuccess
+	}
+}
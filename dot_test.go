@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLattice(t *testing.T) {
+	var lTests = []struct {
+		w      word
+		expect []span
+	}{
+		{word("quartsplat"), []span{{0, 2}, {0, 5}, {2, 5}, {5, 10}}},
+		{word("foobar"), []span{{0, 3}, {3, 6}}},
+		{word("bogus"), nil},
+	}
+
+	for _, tst := range lTests {
+		if actual := lattice(tst.w, testDict); !reflect.DeepEqual(tst.expect, actual) {
+			t.Errorf("lattice - %s: Expected\n\t%v\nBut got\n\t%v", tst.w, tst.expect, actual)
+		}
+	}
+
+	// Stopped words aren't components, so they aren't edges either.
+	stop := graphOf(words{word("art")}, nil)
+	d := dictionary{graph: testGraph, minLen: 2, stop: &stop}
+	if actual, expected := lattice(word("quartsplat"), d), []span{{0, 2}, {0, 5}, {5, 10}}; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("lattice - Expected\n\t%v\nBut got\n\t%v", expected, actual)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	p := potential{whole: word("quartsplat"), prefixes: words{word("quart"), word("qu")}}
+	(&p).isCompound(testDict)
+
+	var out bytes.Buffer
+	if err := writeDOT(&out, p, testDict); err != nil {
+		t.Fatal(err)
+	}
+	dot := out.String()
+
+	for _, expected := range []string{
+		`digraph "quartsplat" {`,
+		`10 [shape=doublecircle];`,
+		`0 -> 2 [label="qu"];`,
+		`2 -> 5 [label="art"];`,
+		`0 -> 5 [label="quart", color=red`,
+		`5 -> 10 [label="splat", color=red`,
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("writeDOT - Missing %q from\n%s", expected, dot)
+		}
+	}
+	if strings.Count(dot, "penwidth=2") != len(p.components) {
+		t.Errorf("writeDOT - Only the chosen path should be highlighted:\n%s", dot)
+	}
+}
+
+func TestDotQuote(t *testing.T) {
+	if actual := dotQuote(`a"b\c`); actual != `"a\"b\\c"` {
+		t.Errorf("dotQuote - Got %s", actual)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// A tracer narrates the search for a way to split a word, as it happens.
+// isCompound and subWords call its methods at every step when their
+// dictionary has one; a nil *tracer ignores them all, so the ordinary
+// search carries on as though it weren't there.
+//
+// Since isCompound hands subWords whatever follows a prefix, and subWords
+// hands itself whatever follows each piece it finds, every word subWords
+// sees is a suffix of the whole word.  The tracer keeps a stack of where
+// each of those suffixes begins, which are exactly the places the whole
+// word has been split so far.
+type tracer struct {
+	out   io.Writer
+	whole word
+	stack []int
+	cover int
+}
+
+// marked writes the whole word with a '|' at each place it's been split,
+// plus one more at extra.
+func (t *tracer) marked(extra int) string {
+	var b strings.Builder
+	cuts := append(append([]int{}, t.stack...), extra)
+	last := 0
+	for _, cut := range cuts {
+		if cut > last {
+			b.Write(t.whole[last:cut])
+			b.WriteByte('|')
+			last = cut
+		}
+	}
+	b.Write(t.whole[last:])
+	return b.String()
+}
+
+func (t *tracer) indent() string {
+	return strings.Repeat("  ", len(t.stack)+1)
+}
+
+// covered notes that the first n bytes of the whole word have been shown
+// to be made of components.
+func (t *tracer) covered(n int) {
+	if n > t.cover {
+		t.cover = n
+	}
+}
+
+func (t *tracer) prefix(pfx word, usable bool) {
+	if t == nil {
+		return
+	}
+	if !usable {
+		fmt.Fprintf(t.out, "Prefix %q is a word, but may not be used as a component.\n", pfx)
+		return
+	}
+	fmt.Fprintf(t.out, "Prefix %q:\n", pfx)
+	t.covered(len(pfx))
+}
+
+func (t *tracer) enter(w word, isWhole bool) {
+	if t == nil {
+		return
+	}
+	at := len(t.whole) - len(w)
+	if len(t.stack) == 0 {
+		if isWhole {
+			fmt.Fprintf(t.out, "%s%s: the remainder %q is a word.\n", t.indent(), t.marked(at), w)
+		} else {
+			fmt.Fprintf(t.out, "%s%s: the remainder %q is not a word, so it has to be split.\n",
+				t.indent(), t.marked(at), w)
+		}
+	}
+	if isWhole {
+		t.covered(len(t.whole))
+	}
+	t.stack = append(t.stack, at)
+}
+
+func (t *tracer) leave() {
+	if t == nil {
+		return
+	}
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+func (t *tracer) tried(pre, rest word, preIsWord, restIsWord bool) {
+	if t == nil {
+		return
+	}
+	at := len(t.whole) - len(pre) - len(rest)
+	mark := t.marked(at + len(pre))
+	switch {
+	case !preIsWord:
+		fmt.Fprintf(t.out, "%s%s: %q is not a word.\n", t.indent(), mark, pre)
+	case restIsWord:
+		fmt.Fprintf(t.out, "%s%s: %q and %q are both words.\n", t.indent(), mark, pre, rest)
+		t.covered(len(t.whole))
+	default:
+		fmt.Fprintf(t.out, "%s%s: %q is a word, but %q is not, so it has to be split.\n",
+			t.indent(), mark, pre, rest)
+		t.covered(at + len(pre))
+	}
+}
+
+// explain writes out why w is or isn't a compound word according to d:
+// which prefixes of w the bytegraph turned up, every split subWords tried
+// and which pieces failed to be words, how much of w could be covered
+// with components if not all of it, and finally the verdict.  w need not
+// be on the list.  It reports whether w is compound.
+func explain(out io.Writer, w word, d dictionary) (bool, error) {
+	t := &tracer{out: out, whole: w}
+	p := potential{whole: w, prefixes: prefixesOf(w, d.graph)}
+
+	fmt.Fprintf(out, "Explaining %q (%d bytes):\n", w, len(w))
+	if isWord(w, d.graph) {
+		fmt.Fprintf(out, "It is on the list itself, which doesn't count towards being compound.\n")
+	}
+	if len(p.prefixes) == 0 {
+		fmt.Fprintf(out, "No word on the list is a prefix of it, so it cannot be compound.\n")
+	} else {
+		pfxs := make([]string, 0, len(p.prefixes))
+		for _, pfx := range p.prefixes {
+			pfxs = append(pfxs, string(pfx))
+		}
+		fmt.Fprintf(out, "Prefixes found on the graph: %s\n", strings.Join(pfxs, ", "))
+	}
+
+	// The search which gets traced is always the greedy one, since
+	// that's the one which works piece by piece; any other strategy
+	// then gets the final say on which split is best.
+	traced := d
+	traced.strategy, traced.trace = strategyGreedy, t
+	compound := (&p).isCompound(traced)
+	if compound && d.chosenStrategy() != strategyGreedy {
+		p.components = nil
+		(&p).isCompound(d)
+	}
+
+	if !compound && t.cover > 0 {
+		fmt.Fprintf(out, "The most of it that could be covered was %d of %d bytes, %s, leaving %q.\n",
+			t.cover, len(w), coverOf(w[:t.cover], d), w[t.cover:])
+	}
+	_, err := fmt.Fprintf(out, "Result: %v\n", p)
+	return compound, err
+}
+
+// coverOf shows how a covered stretch of a word breaks into components.
+func coverOf(covered word, d dictionary) string {
+	parts := subWords(covered, d)
+	s := make([]string, 0, len(parts))
+	for _, part := range parts {
+		s = append(s, string(part))
+	}
+	return strings.Join(s, " + ")
+}
+
+// explainFlags are the flags of the explain command, which takes the word
+// to explain, and then the word lists to explain it with.
+func explainFlags(fs *flag.FlagSet) action {
+	var sf searchFlags
+	sf.register(fs)
+
+	return func(fs *flag.FlagSet) int {
+		if fs.NArg() == 0 {
+			return misuse(fs, errors.New("no word to explain"))
+		}
+		s, status := setUpSearch(fs, sf, fs.Args()[1:])
+		if s == nil {
+			return status
+		}
+		compound, err := explain(os.Stdout, word(fs.Arg(0)), s.dict)
+		if err != nil {
+			complain(err)
+			return exitIO
+		}
+		if !compound {
+			return exitNoCompound
+		}
+		return exitSuccess
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	var eTests = []struct {
+		w       word
+		expect  bool
+		mention []string
+	}{
+		{word("quartsplat"), true, []string{
+			"Prefixes found on the graph: quart, qu",
+			`quart|splat: the remainder "splat" is a word.`,
+			"Result: quartsplat = quart + splat",
+		}},
+		{word("fooartfulbar"), true, []string{
+			`foo|artfulbar: the remainder "artfulbar" is not a word`,
+			`foo|artful|bar: "artful" and "bar" are both words.`,
+		}},
+		{word("quartfulsquishy"), false, []string{
+			`qu|artful|squishy: "artful" is a word, but "squishy" is not`,
+			`"squis" is not a word.`,
+			`covered was 8 of 15 bytes, qu + artful, leaving "squishy"`,
+			"[NOT COMPOUND]",
+		}},
+		{word("bogus"), false, []string{
+			"No word on the list is a prefix of it",
+		}},
+		{word("foobar"), true, []string{
+			"It is on the list itself",
+		}},
+	}
+
+	for _, tst := range eTests {
+		var out bytes.Buffer
+		actual, err := explain(&out, tst.w, testDict)
+		if err != nil || actual != tst.expect {
+			t.Errorf("explain - %s came back %v / expected %v (error %v)", tst.w, actual, tst.expect, err)
+		}
+		for _, m := range tst.mention {
+			if !strings.Contains(out.String(), m) {
+				t.Errorf("explain - %s: missing %q from\n%s", tst.w, m, out.String())
+			}
+		}
+	}
+}
+
+func TestExplainStopped(t *testing.T) {
+	stop := graphOf(words{word("quart")}, nil)
+	d := dictionary{graph: testGraph, minLen: 2, stop: &stop}
+
+	var out bytes.Buffer
+	if _, err := explain(&out, word("quartsplat"), d); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `Prefix "quart" is a word, but may not be used`) {
+		t.Errorf("explain - A stopped prefix should be pointed out:\n%s", out.String())
+	}
+}
+
+// The tracer must not change what the search finds, and a nil tracer
+// must be safe to call.
+func TestTracerQuiet(t *testing.T) {
+	var nobody *tracer
+	nobody.prefix(word("foo"), true)
+	nobody.enter(word("foo"), true)
+	nobody.tried(word("foo"), word("bar"), true, true)
+	nobody.leave()
+
+	var out bytes.Buffer
+	traced := testDict
+	traced.trace = &tracer{out: &out, whole: word("fooquuxsquish")}
+	expected := subWords(word("quuxsquish"), testDict)
+	actual := subWords(word("quuxsquish"), traced)
+	if len(expected) != len(actual) || len(traced.trace.stack) != 0 {
+		t.Errorf("tracer - Expected %q but got %q (stack %v)", expected, actual, traced.trace.stack)
+	}
+}
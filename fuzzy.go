@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+)
+
+// The measures of how far apart a piece of a word and a dictionary word
+// may be.  Both count inserting, deleting and changing a byte as an edit;
+// Damerau distance also counts swapping two neighbouring bytes as one,
+// where Levenshtein distance counts it as two.
+const (
+	distanceLevenshtein = "levenshtein"
+	distanceDamerau     = "damerau"
+)
+
+// A fuzziness says how far each component of a compound word may be from
+// the dictionary word it's taken for.  A maxEdits of zero means they must
+// match exactly, as they always have.
+type fuzziness struct {
+	maxEdits  int
+	transpose bool
+}
+
+func (f *fuzziness) register(fs *flag.FlagSet, distance *string) {
+	fs.IntVar(&f.maxEdits, "fuzzy", 0, "most `edits` a component may be from a word (0 for exact matches only)")
+	fs.StringVar(distance, "distance", distanceLevenshtein, "how to count edits: `levenshtein|damerau`")
+}
+
+// finish works out whether swaps count as one edit, once the flags have
+// been parsed.
+func (f *fuzziness) finish(distance string) error {
+	if f.maxEdits < 0 {
+		return fmt.Errorf("bad -fuzzy %d", f.maxEdits)
+	}
+	switch distance {
+	case distanceLevenshtein:
+		f.transpose = false
+	case distanceDamerau:
+		f.transpose = true
+	default:
+		return fmt.Errorf("unknown -distance %q (try %s or %s)", distance, distanceLevenshtein, distanceDamerau)
+	}
+	return nil
+}
+
+// A fuzzyMatch is a dictionary word which the first length bytes of some
+// text might have been meant to be, cost edits away from them.
+type fuzzyMatch struct {
+	w      word
+	length int
+	cost   int
+}
+
+// fuzzyPrefixes finds every word on g which is within f.maxEdits of some
+// prefix of s, and which d allows as a component.  They come cheapest
+// first, then longest piece of s first, then in order, so that the same
+// text is always read the same way.
+//
+// It's the usual dynamic programming for edit distance, done on the
+// graph rather than on one word at a time: each step down the graph adds
+// a row to the table, giving the distance from the path so far to every
+// prefix of s.  Words which share a beginning share the rows for it, and
+// as soon as every entry in a row is over the limit, nothing further down
+// that branch can come back under it, so the walk turns back.
+func fuzzyPrefixes(s word, g bytegraph, d dictionary, f fuzziness) (matches []fuzzyMatch) {
+	first := make([]int, len(s)+1)
+	for j := range first {
+		first[j] = j
+	}
+	path := make(word, 0, len(s)+f.maxEdits)
+
+	var walk func(g bytegraph, before, row []int)
+	walk = func(g bytegraph, before, row []int) {
+		if len(path) >= len(s)+f.maxEdits {
+			return
+		}
+		for b, next := range g.next {
+			path = append(path, b)
+			k := len(path)
+			nextRow := make([]int, len(s)+1)
+			nextRow[0] = k
+			closest := nextRow[0]
+			for j := 1; j <= len(s); j++ {
+				change := row[j-1]
+				if s[j-1] != b {
+					change++
+				}
+				nextRow[j] = min(row[j]+1, nextRow[j-1]+1, change)
+				if f.transpose && k > 1 && j > 1 && s[j-1] == path[k-2] && s[j-2] == b {
+					nextRow[j] = min(nextRow[j], before[j-2]+1)
+				}
+				closest = min(closest, nextRow[j])
+			}
+
+			if next.endOfWord && d.isComponent(path) {
+				for j := 1; j <= len(s); j++ {
+					if nextRow[j] <= f.maxEdits {
+						matches = append(matches, fuzzyMatch{append(word(nil), path...), j, nextRow[j]})
+					}
+				}
+			}
+			if closest <= f.maxEdits {
+				walk(next, row, nextRow)
+			}
+			path = path[:k-1]
+		}
+	}
+	walk(g, nil, first)
+
+	sort.Slice(matches, func(i, j int) bool {
+		mi, mj := matches[i], matches[j]
+		if mi.cost != mj.cost {
+			return mi.cost < mj.cost
+		}
+		if mi.length != mj.length {
+			return mi.length > mj.length
+		}
+		return string(mi.w) < string(mj.w)
+	})
+	return
+}
+
+// fuzzySplit finds the cheapest way of reading w as two or more words from
+// d, each within d.fuzz.maxEdits of the piece of w it stands for, in the
+// same way fewestSplit finds the shortest.  Of several ways with the same
+// total cost, the one with the fewest components wins.  It returns the
+// dictionary words, where in w each one's piece begins, and the total
+// number of edits; if w can't be read that way, ws is nil.
+func fuzzySplit(w word, d dictionary) (ws words, offsets []int, cost int) {
+	type step struct {
+		cost, parts int
+		start, k    int // Where the one before ends, and how many it makes.
+		w           word
+	}
+	// best[i][k] is the cheapest way of reading the first i bytes of w as
+	// k components, where k of 2 stands for two or more.
+	n := len(w)
+	best := make([][3]*step, n+1)
+	best[0][0] = &step{}
+	better := func(s *step, than *step) bool {
+		return than == nil || s.cost < than.cost || (s.cost == than.cost && s.parts < than.parts)
+	}
+
+	for j := 0; j < n; j++ {
+		if best[j] == [3]*step{} {
+			continue
+		}
+		for _, m := range fuzzyPrefixes(w[j:], d.graph, d, d.fuzz) {
+			for k, from := range best[j] {
+				if from == nil {
+					continue
+				}
+				s := &step{from.cost + m.cost, from.parts + 1, j, k, m.w}
+				to := &best[j+m.length][min(k+1, 2)]
+				if better(s, *to) {
+					*to = s
+				}
+			}
+		}
+	}
+
+	if best[n][2] == nil {
+		return nil, nil, 0
+	}
+	cost = best[n][2].cost
+	for s := best[n][2]; s.parts > 0; s = best[s.start][s.k] {
+		ws = append(words{s.w}, ws...)
+		offsets = append([]int{s.start}, offsets...)
+	}
+	return
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzinessFinish(t *testing.T) {
+	f := fuzziness{maxEdits: 1}
+	if err := f.finish(distanceDamerau); err != nil || !f.transpose {
+		t.Errorf("fuzziness.finish - Got %+v (error %v)", f, err)
+	}
+	if err := f.finish(distanceLevenshtein); err != nil || f.transpose {
+		t.Errorf("fuzziness.finish - Got %+v (error %v)", f, err)
+	}
+	if err := f.finish("hamming"); err == nil {
+		t.Errorf("fuzziness.finish - Expected an error for hamming")
+	}
+	f.maxEdits = -1
+	if err := f.finish(distanceLevenshtein); err == nil {
+		t.Errorf("fuzziness.finish - Expected an error for %d edits", f.maxEdits)
+	}
+}
+
+func TestFuzzyPrefixes(t *testing.T) {
+	matches := fuzzyPrefixes(word("quxrtful"), testGraph, testDict, fuzziness{maxEdits: 1})
+	found := make(map[string]bool)
+	for _, m := range matches {
+		found[string(m.w)+"/"+string(rune('0'+m.length))+"/"+string(rune('0'+m.cost))] = true
+		if m.cost > 1 {
+			t.Errorf("fuzzyPrefixes - %s is %d edits away", m.w, m.cost)
+		}
+	}
+	for _, expected := range []string{"qu/2/0", "quart/5/1", "qu/3/1"} {
+		if !found[expected] {
+			t.Errorf("fuzzyPrefixes - Expected to find %s among %v", expected, matches)
+		}
+	}
+	if matches[0].cost != 0 {
+		t.Errorf("fuzzyPrefixes - The cheapest match should come first, got %v", matches[0])
+	}
+
+	if matches = fuzzyPrefixes(word("zzzz"), testGraph, testDict, fuzziness{maxEdits: 1}); len(matches) != 0 {
+		t.Errorf("fuzzyPrefixes - Expected nothing, got %v", matches)
+	}
+}
+
+func TestFuzzySplit(t *testing.T) {
+	var fTests = []struct {
+		w       word
+		fuzz    fuzziness
+		parts   words
+		offsets []int
+		cost    int
+	}{
+		{word("fobar"), fuzziness{1, false}, words{word("foo"), word("bar")}, []int{0, 2}, 1},
+		{word("qaurtsplat"), fuzziness{1, true}, words{word("quart"), word("splat")}, []int{0, 5}, 1},
+		{word("qaurtsplat"), fuzziness{1, false}, words{word("qu"), word("art"), word("splat")}, []int{0, 1, 5}, 2},
+		{word("foobarquux"), fuzziness{1, false}, words{word("foobar"), word("quux")}, []int{0, 6}, 0},
+		{word("zzzzzz"), fuzziness{1, false}, nil, nil, 0},
+	}
+	for _, ft := range fTests {
+		d := testDict
+		d.fuzz = ft.fuzz
+		parts, offsets, cost := fuzzySplit(ft.w, d)
+		if !reflect.DeepEqual(parts, ft.parts) || !reflect.DeepEqual(offsets, ft.offsets) || cost != ft.cost {
+			t.Errorf("fuzzySplit - For %s with %+v, expected %q at %v costing %d, got %q at %v costing %d",
+				ft.w, ft.fuzz, ft.parts, ft.offsets, ft.cost, parts, offsets, cost)
+		}
+	}
+}
+
+func TestIsCompoundFuzzy(t *testing.T) {
+	d := testDict
+	d.fuzz = fuzziness{maxEdits: 1}
+
+	// An exact split is always preferred.
+	results, _ := checkWords(words{word("foobar"), word("fobar"), word("zzzzzz")}, d)
+	expected := []string{"foobar = foo + bar", "fobar = foo + bar [edit cost = 1]", "zzzzzz [NOT COMPOUND]"}
+	for i, r := range results {
+		if r.String() != expected[i] {
+			t.Errorf("isCompound - Expected %q, got %q", expected[i], r.String())
+		}
+	}
+
+	r := results[1].record()
+	if r.Cost != 1 || r.Components[1].Offset != 2 || string(results[1].piece(1)) != "bar" ||
+		string(results[1].piece(0)) != "fo" {
+		t.Errorf("record - Got %+v", r)
+	}
+
+	// A word on the list is spelled right, so it isn't read as a
+	// misspelled compound, even though qu + quux is only an edit away.
+	if results, _ = checkWords(words{word("quux")}, d); results[0].String() != "quux [NOT COMPOUND]" {
+		t.Errorf("isCompound - Expected quux to be left alone, got %q", results[0].String())
+	}
+}
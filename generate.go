@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// triesPerName is how many random combinations generate draws, for each
+// name it's been asked for, before it gives up on finding any more.
+const triesPerName = 1000
+
+// A generator proposes compound words which aren't on the list, made by
+// joining two or more of its components at random.  The rest of its
+// fields narrow down which of those are worth proposing.  Drawing from
+// the same rng always proposes the same names, so a seed is all it takes
+// to get them again.
+type generator struct {
+	dict       dictionary
+	components words
+	parts      int
+	minLen     int
+	maxLen     int
+	pattern    *regexp.Regexp
+	avoid      *bytegraph
+	rng        *rand.Rand
+}
+
+// newGenerator gathers every word on ws which may be used as a component,
+// in sorted order, so that what's drawn from them depends only on the
+// seed, and not on the order the lists were read in.
+func newGenerator(d dictionary, ws *wordset, seed int64) *generator {
+	g := &generator{dict: d, parts: 2, rng: rand.New(rand.NewSource(seed))}
+	for _, w := range ws.list {
+		if d.isComponent(w) {
+			g.components = append(g.components, w)
+		}
+	}
+	sort.Sort(g.components)
+	return g
+}
+
+// propose draws combinations until it has n that are acceptable, or until
+// it has drawn triesPerName for each of them.  It returns the names in
+// the order they were found, and how many combinations it drew.
+func (g *generator) propose(n int) (names potentials, tries int) {
+	if len(g.components) == 0 {
+		return
+	}
+	seen := make(map[string]bool)
+	for ; len(names) < n && tries < n*triesPerName; tries++ {
+		parts := make(words, 2+g.rng.Intn(g.parts-1))
+		var whole word
+		for i := range parts {
+			parts[i] = g.components[g.rng.Intn(len(g.components))]
+			whole = append(whole, parts[i]...)
+		}
+		if seen[string(whole)] || !g.acceptable(whole) {
+			continue
+		}
+		seen[string(whole)] = true
+		names = append(names, potential{whole: whole, components: parts})
+	}
+	return
+}
+
+// acceptable tells whether w would make a good name: it isn't already on
+// the list, it's the right length, it matches the pattern, and it doesn't
+// have an unwanted word hiding in it, perhaps across a join.
+func (g *generator) acceptable(w word) bool {
+	return len(w) >= g.minLen &&
+		(g.maxLen == 0 || len(w) <= g.maxLen) &&
+		!isWord(w, g.dict.graph) &&
+		(g.pattern == nil || g.pattern.Match(w)) &&
+		(g.avoid == nil || containsWord(w, *g.avoid) == nil)
+}
+
+// combinations counts how many names could be made of between two and
+// parts components, of a length between minLen and maxLen, before any are
+// ruled out for being on the list, missing the pattern or containing an
+// unwanted word.  A maxLen of zero means there's no limit.  There can be
+// more of them than a uint64 holds, in which case it's math.MaxUint64.
+func (g *generator) combinations() (total uint64) {
+	byLength := make(map[int]uint64)
+	for _, w := range g.components {
+		byLength[len(w)]++
+	}
+	// ways[l] is how many ways there are to make l bytes out of the
+	// number of components joined so far.
+	ways := map[int]uint64{0: 1}
+	for joined := 1; joined <= g.parts; joined++ {
+		next := make(map[int]uint64)
+		for l, n := range ways {
+			for wl, count := range byLength {
+				if g.maxLen == 0 || l+wl <= g.maxLen {
+					next[l+wl] = addSaturating(next[l+wl], mulSaturating(n, count))
+				}
+			}
+		}
+		ways = next
+		if joined < 2 {
+			continue
+		}
+		for l, n := range ways {
+			if l >= g.minLen {
+				total = addSaturating(total, n)
+			}
+		}
+	}
+	return
+}
+
+// addSaturating and mulSaturating do arithmetic which stops at
+// math.MaxUint64, rather than wrapping around to something small.
+func addSaturating(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return sum
+}
+
+func mulSaturating(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	if hi != 0 {
+		return math.MaxUint64
+	}
+	return lo
+}
+
+// containsWord returns the first word on g found anywhere inside w, or
+// nil if there isn't one.
+func containsWord(w word, g bytegraph) word {
+	for start := range w {
+		node := g
+		for i := start; i < len(w); i++ {
+			next, exists := node.next[w[i]]
+			if !exists {
+				break
+			}
+			node = next
+			if node.endOfWord {
+				return w[start : i+1]
+			}
+		}
+	}
+	return nil
+}
+
+// generateFlags are the flags of the generate command, which proposes
+// names made of words from the lists that aren't on them already.
+func generateFlags(fs *flag.FlagSet) action {
+	count := fs.Int("count", 10, "how many `names` to propose")
+	parts := fs.Int("parts", 2, "most `words` to join into a name: 2 or 3")
+	minLen := fs.Int("min", 0, "shortest name to propose, in `bytes`")
+	maxLen := fs.Int("max", 0, "longest name to propose, in `bytes` (0 for no limit)")
+	pattern := fs.String("pattern", "", "`regexp` the names must match")
+	avoid := fs.String("avoid", "", "`file` of words the names must not contain")
+	seed := fs.Int64("seed", 1, "`number` to seed the random choice of words with")
+	var df dictionaryFlags
+	df.register(fs)
+	var outOpts outputOptions
+	outOpts.register(fs)
+
+	return func(fs *flag.FlagSet) int {
+		if fs.NArg() == 0 {
+			return misuse(fs, errors.New("no word lists given"))
+		}
+		if *parts < 2 || *parts > 3 {
+			return misuse(fs, fmt.Errorf("bad -parts %d", *parts))
+		}
+		if *count < 1 || *minLen < 0 || *maxLen < 0 || (*maxLen > 0 && *maxLen < *minLen) {
+			return misuse(fs, fmt.Errorf("bad -count %d, -min %d or -max %d", *count, *minLen, *maxLen))
+		}
+		var re *regexp.Regexp
+		if *pattern != "" {
+			var err error
+			if re, err = regexp.Compile(*pattern); err != nil {
+				return misuse(fs, fmt.Errorf("bad -pattern: %v", err))
+			}
+		}
+		if err := df.finish(); err != nil {
+			return misuse(fs, err)
+		}
+		format, err := outOpts.formatterFor(os.Stdout)
+		if err != nil {
+			return misuse(fs, fmt.Errorf("bad output settings: %v", err))
+		}
+
+		dict, ws, err := loadDictionary(fs.Args(), df)
+		if err != nil {
+			return cantLoad(fs, err)
+		}
+		g := newGenerator(dict, ws, *seed)
+		g.parts, g.minLen, g.maxLen, g.pattern = *parts, *minLen, *maxLen, re
+		if g.avoid, err = loadWordGraph(*avoid, df.load); err != nil {
+			complain(err)
+			return exitIO
+		}
+
+		names, tries := g.propose(*count)
+		if err = format(os.Stdout, names); err != nil {
+			complain(err)
+			return exitIO
+		}
+		total := g.combinations()
+		combinations := fmt.Sprint(total)
+		if total == math.MaxUint64 {
+			combinations = "at least " + combinations
+		}
+		fmt.Fprintf(os.Stderr, "Proposed %d names in %d tries, out of %s combinations of the right length.\n",
+			len(names), tries, combinations)
+		if len(names) == 0 {
+			return exitNoCompound
+		}
+		return exitSuccess
+	}
+}
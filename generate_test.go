@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// testGenerator makes a generator over the test words.
+func testGenerator(seed int64) *generator {
+	ws := newWordset()
+	for i, w := range testWords {
+		ws.add(w, 0, occurrence{"test.list", i + 1})
+	}
+	return newGenerator(testDict, ws, seed)
+}
+
+func TestContainsWord(t *testing.T) {
+	g := graphOf(words{word("art"), word("oba")}, nil)
+	var cTests = []struct {
+		w        word
+		expected word
+	}{
+		{word("quartsplat"), word("art")},
+		{word("foobar"), word("oba")}, // Across the join of foo and bar.
+		{word("splatfoo"), nil},
+		{word("ar"), nil},
+	}
+	for _, ct := range cTests {
+		if actual := containsWord(ct.w, g); !bytes.Equal(actual, ct.expected) {
+			t.Errorf("containsWord - For %s, expected %q, got %q", ct.w, ct.expected, actual)
+		}
+	}
+}
+
+func TestGeneratorPropose(t *testing.T) {
+	g := testGenerator(7)
+	g.parts, g.minLen, g.maxLen = 3, 6, 12
+	names, tries := g.propose(5)
+	if len(names) != 5 || tries < 5 {
+		t.Fatalf("generator.propose - Got %v in %d tries", names, tries)
+	}
+	for _, p := range names {
+		if len(p.whole) < 6 || len(p.whole) > 12 || isWord(p.whole, testGraph) ||
+			len(p.components) < 2 || len(p.components) > 3 ||
+			string(p.whole) != joined(p.components) {
+			t.Errorf("generator.propose - %s shouldn't have been proposed", p)
+		}
+	}
+
+	again := testGenerator(7)
+	again.parts, again.minLen, again.maxLen = 3, 6, 12
+	if repeated, _ := again.propose(5); !reflect.DeepEqual(names, repeated) {
+		t.Errorf("generator.propose - The same seed gave %v, then %v", names, repeated)
+	}
+
+	g = testGenerator(7)
+	g.pattern = regexp.MustCompile("^foo")
+	avoid := graphOf(words{word("art")}, nil)
+	g.avoid = &avoid
+	names, _ = g.propose(5)
+	for _, p := range names {
+		if !bytes.HasPrefix(p.whole, word("foo")) || bytes.Contains(p.whole, word("art")) {
+			t.Errorf("generator.propose - %s shouldn't have been proposed", p)
+		}
+	}
+
+	// Only one name can match, so every try gets used looking for another.
+	g = testGenerator(1)
+	g.components = words{word("foo"), word("bar")}
+	g.avoid = &avoid
+	g.pattern = regexp.MustCompile("^barbar$")
+	if names, tries = g.propose(2); len(names) != 1 || tries != 2*triesPerName {
+		t.Errorf("generator.propose - Got %v in %d tries", names, tries)
+	}
+}
+
+func TestGeneratorCombinations(t *testing.T) {
+	g := &generator{components: words{word("ab"), word("cd"), word("efg")}}
+	var cTests = []struct {
+		parts, minLen, maxLen int
+		expected              uint64
+	}{
+		{2, 0, 0, 9},
+		{2, 5, 0, 5},
+		{2, 0, 4, 4},
+		{3, 0, 6, 17},
+		{3, 9, 9, 1},
+	}
+	for _, ct := range cTests {
+		g.parts, g.minLen, g.maxLen = ct.parts, ct.minLen, ct.maxLen
+		if actual := g.combinations(); actual != ct.expected {
+			t.Errorf("generator.combinations - For %+v, expected %d, got %d", ct, ct.expected, actual)
+		}
+	}
+
+	// Ten one-letter words make 10^20 names of twenty components, which is
+	// more than a uint64 holds.
+	g = &generator{parts: 20}
+	for b := byte('a'); b <= 'j'; b++ {
+		g.components = append(g.components, word{b})
+	}
+	if actual := g.combinations(); actual != math.MaxUint64 {
+		t.Errorf("generator.combinations - Expected %d when they overflow, got %d", uint64(math.MaxUint64), actual)
+	}
+}
+
+func joined(ws words) (s string) {
+	for _, w := range ws {
+		s += string(w)
+	}
+	return
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A formatter writes out a batch of results.  Whatever mode compound is
+// running in, everything it has to say about the words it looked at goes
+// through one of these, so that every mode can speak every format.
+type formatter func(out io.Writer, results potentials) error
+
+// formatters maps the names accepted by -format to the formatters
+// themselves, for the formats which don't need any settings.
+var formatters = map[string]formatter{
+	"text":   writeText,
+	"json":   writeJSON,
+	"ndjson": writeNDJSON,
+}
+
+// delimiters are the formats which write delimited rows, and the
+// separators they use unless told otherwise.
+var delimiters = map[string]rune{
+	"csv": ',',
+	"tsv": '\t',
+}
+
+// defaultJoin is what joins the components in delimited rows unless
+// told otherwise.
+const defaultJoin = "+"
+
+// outputOptions are everything the command line (and the environment)
+// has to say about how results are written.  terminal is whether they're
+// going to one, and noColor whether NO_COLOR is set to anything.
+type outputOptions struct {
+	format    string
+	separator string
+	join      string
+	header    bool
+	color     string
+	terminal  bool
+	noColor   bool
+	template  string
+}
+
+// The settings -color accepts.
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// useColor decides whether to color the output.  Left to decide for
+// itself, compound only colors output going to a terminal, and not even
+// then if NO_COLOR is set (see https://no-color.org).
+func (o outputOptions) useColor() bool {
+	switch o.color {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+	return o.terminal && !o.noColor
+}
+
+func (o *outputOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "format", "text", "output format: `text|human|json|ndjson|csv|tsv`")
+	fs.StringVar(&o.separator, "sep", "", "separator `char` for csv and tsv output")
+	fs.StringVar(&o.join, "join", defaultJoin, "`text` to join components with in csv and tsv output")
+	fs.BoolVar(&o.header, "header", true, "start csv and tsv output with column names")
+	fs.StringVar(&o.color, "color", colorAuto, "color human output: `auto|always|never`")
+	fs.StringVar(&o.template, "template", "", "write each result through this text/template `text`")
+}
+
+// formatterFor returns the formatter the options call for, given that
+// its output is going to f.
+func (o outputOptions) formatterFor(f *os.File) (formatter, error) {
+	o.terminal = isTerminal(f)
+	o.noColor = noColorSet()
+	return o.formatter()
+}
+
+// noColorSet tells whether NO_COLOR asks for no color.  Only a value
+// that isn't empty does; see https://no-color.org.
+func noColorSet() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// formatter returns the formatter the options call for.  A template
+// trumps any format.  Plain text going to a terminal is written for people
+// to read rather than for scripts to parse; see newHuman.
+func (o outputOptions) formatter() (formatter, error) {
+	if o.template != "" {
+		return newTemplate(o.template)
+	}
+	switch o.color {
+	case colorAuto, colorAlways, colorNever, "":
+	default:
+		return nil, fmt.Errorf("unknown color setting %q", o.color)
+	}
+	if o.format == "human" || (o.format == "text" && (o.terminal || o.color == colorAlways)) {
+		return newHuman(o.useColor()), nil
+	}
+
+	if sep, delimited := delimiters[o.format]; delimited {
+		if o.separator != "" {
+			if utf8.RuneCountInString(o.separator) != 1 {
+				return nil, fmt.Errorf("separator %q must be a single character", o.separator)
+			}
+			sep, _ = utf8.DecodeRuneInString(o.separator)
+		}
+		join := o.join
+		if join == "" {
+			join = defaultJoin
+		}
+		return newDelimited(sep, join, o.header), nil
+	}
+	if f, known := formatters[o.format]; known {
+		return f, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", o.format)
+}
+
+// writeText is the original output: one potential.String() per line.
+func writeText(out io.Writer, results potentials) error {
+	for _, p := range results {
+		if _, err := fmt.Fprintln(out, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A record is a potential as presented to other programs, with all the
+// measurements they'd otherwise have to make for themselves.  Bytes and
+// offsets are counted in bytes, Runes in runes.
+type record struct {
+	Word       string      `json:"word"`
+	Bytes      int         `json:"bytes"`
+	Runes      int         `json:"runes"`
+	Compound   bool        `json:"compound"`
+	Components []component `json:"components"`
+	Score      float64     `json:"score,omitempty"`
+	Cost       int         `json:"cost,omitempty"`
+	Sources    []string    `json:"sources,omitempty"`
+}
+
+// A component is one of the words a compound word is made of, and where
+// in the compound word it begins.
+type component struct {
+	Word   string `json:"word"`
+	Offset int    `json:"offset"`
+}
+
+func (p potential) record() (r record) {
+	r.Word = string(p.whole)
+	r.Bytes = len(p.whole)
+	r.Runes = utf8.RuneCount(p.whole)
+	r.Compound = len(p.components) > 0
+	r.Components = make([]component, 0, len(p.components))
+	offset := 0
+	for i, c := range p.components {
+		if p.offsets != nil {
+			offset = p.offsets[i]
+		}
+		r.Components = append(r.Components, component{Word: string(c), Offset: offset})
+		offset += len(c)
+	}
+	r.Score = p.score
+	r.Cost = p.cost
+	r.Sources = p.sources
+	return
+}
+
+// piece returns the part of the whole word which its i'th component
+// stands for.  That's the component itself, unless it was a fuzzy match.
+func (p potential) piece(i int) word {
+	if p.offsets == nil {
+		return p.components[i]
+	}
+	end := len(p.whole)
+	if i+1 < len(p.offsets) {
+		end = p.offsets[i+1]
+	}
+	return p.whole[p.offsets[i]:end]
+}
+
+// writeJSON writes all the results as a single JSON array.
+func writeJSON(out io.Writer, results potentials) error {
+	records := make([]record, 0, len(results))
+	for _, p := range results {
+		records = append(records, p.record())
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+// writeNDJSON writes each result as a JSON object on a line of its own.
+func writeNDJSON(out io.Writer, results potentials) error {
+	enc := json.NewEncoder(out)
+	for _, p := range results {
+		if err := enc.Encode(p.record()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// delimitedHeader names the columns written by newDelimited formatters.
+var delimitedHeader = []string{"word", "length", "count", "components"}
+
+// newDelimited returns a formatter which writes one row per result, for
+// loading into spreadsheets and databases: the word, its length in bytes,
+// how many components it has, and the components themselves joined by
+// join.  Every row has the same four columns, so that fixed-column
+// imports can take them.  Fields holding the separator (or quotes, or
+// line breaks) are quoted the way encoding/csv does it, but a component
+// holding join can't be told from two; for words like "c++", pick a join
+// which no word on the lists contains, such as a space.
+func newDelimited(sep rune, join string, header bool) formatter {
+	return func(out io.Writer, results potentials) error {
+		w := csv.NewWriter(out)
+		w.Comma = sep
+		if header {
+			if err := w.Write(delimitedHeader); err != nil {
+				return err
+			}
+		}
+		for _, p := range results {
+			parts := make([]byte, 0, len(p.whole)+len(join)*len(p.components))
+			for i, c := range p.components {
+				if i > 0 {
+					parts = append(parts, join...)
+				}
+				parts = append(parts, c...)
+			}
+			row := []string{
+				string(p.whole),
+				strconv.Itoa(len(p.whole)),
+				strconv.Itoa(len(p.components)),
+				string(parts),
+			}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// The colors newHuman takes turns with, and the code to go back to normal.
+var (
+	componentColors = []string{"\x1b[36m", "\x1b[33m"}
+	colorReset      = "\x1b[0m"
+)
+
+// newHuman returns a formatter for people reading at a terminal.  The
+// words are padded so that the " = " after each of them lines up, and
+// with color on, each component of a compound word is shown in a
+// different color from its neighbours, both in the word itself and in
+// the list of components, so the boundaries stand out.
+func newHuman(color bool) formatter {
+	return func(out io.Writer, results potentials) error {
+		width := 0
+		for _, p := range results {
+			if n := utf8.RuneCount(p.whole); n > width {
+				width = n
+			}
+		}
+
+		for _, p := range results {
+			var b strings.Builder
+			pad := strings.Repeat(" ", width-utf8.RuneCount(p.whole))
+			if len(p.components) == 0 {
+				fmt.Fprintf(&b, "%s%s [NOT COMPOUND]", p.whole, pad)
+			} else {
+				paint := func(i int, c word) string {
+					if !color {
+						return string(c)
+					}
+					return componentColors[i%len(componentColors)] + string(c) + colorReset
+				}
+				parts := make([]string, 0, len(p.components))
+				for i, c := range p.components {
+					b.WriteString(paint(i, p.piece(i)))
+					parts = append(parts, paint(i, c))
+				}
+				fmt.Fprintf(&b, "%s = %s", pad, strings.Join(parts, " + "))
+				if p.score != 0 {
+					fmt.Fprintf(&b, " [log P = %.2f]", p.score)
+				}
+				if p.cost != 0 {
+					fmt.Fprintf(&b, " [edit cost = %d]", p.cost)
+				}
+			}
+			if _, err := fmt.Fprintln(out, b.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// templateData is what a -template gets to work with for each result.
+// Length is in bytes, Parts is the number of components, and Source is
+// the first file the word was found in.
+type templateData struct {
+	Word       string
+	Length     int
+	Runes      int
+	Compound   bool
+	Components []string
+	Parts      int
+	Offsets    []int
+	Score      float64
+	Cost       int
+	Source     string
+	Sources    []string
+}
+
+func (p potential) templateData() (td templateData) {
+	r := p.record()
+	td.Word, td.Length, td.Runes, td.Compound = r.Word, r.Bytes, r.Runes, r.Compound
+	td.Components = make([]string, 0, len(r.Components))
+	td.Offsets = make([]int, 0, len(r.Components))
+	for _, c := range r.Components {
+		td.Components = append(td.Components, c.Word)
+		td.Offsets = append(td.Offsets, c.Offset)
+	}
+	td.Parts = len(td.Components)
+	td.Score = r.Score
+	td.Cost = r.Cost
+	td.Sources = r.Sources
+	if len(td.Sources) > 0 {
+		td.Source = td.Sources[0]
+	}
+	return
+}
+
+// templateFuncs are the helpers available to a -template, on top of the
+// ones text/template always has.
+var templateFuncs = template.FuncMap{
+	"join":  func(elems []string, sep string) string { return strings.Join(elems, sep) },
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"title": title,
+}
+
+// title capitalizes the first letter of s, and leaves the rest alone.
+func title(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToTitle(r)) + s[size:]
+}
+
+// newTemplate returns a formatter which renders each result through a
+// text/template, one result per line.  {{join .Components "-"}} renders
+// "foobar" as "foo-bar", for instance.  See templateData for what's
+// available to it.
+func newTemplate(text string) (formatter, error) {
+	tmpl, err := template.New("result").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return func(out io.Writer, results potentials) error {
+		for _, p := range results {
+			if err := tmpl.Execute(out, p.templateData()); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(out, "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testResults = potentials{
+	{whole: word("quartsplat"),
+		components: words{word("quart"), word("splat")},
+		sources:    []string{"test.list"}},
+	{whole: word("naïveté"),
+		components: nil},
+}
+
+func TestRecord(t *testing.T) {
+	expected := record{
+		Word: "quartsplat", Bytes: 10, Runes: 10, Compound: true,
+		Components: []component{{"quart", 0}, {"splat", 5}},
+		Sources:    []string{"test.list"},
+	}
+	if actual := testResults[0].record(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("record - Expected\n\t%+v\nBut got\n\t%+v", expected, actual)
+	}
+
+	// Bytes and runes part ways outside of ASCII, and a word that isn't
+	// compound still gets an (empty) list of components.
+	actual := testResults[1].record()
+	if actual.Bytes != 9 || actual.Runes != 7 || actual.Compound || actual.Components == nil {
+		t.Errorf("record - Got %+v for %s", actual, testResults[1].whole)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeText(&out, testResults); err != nil {
+		t.Fatal(err)
+	}
+	expected := "quartsplat = quart + splat\nnaïveté [NOT COMPOUND]\n"
+	if out.String() != expected {
+		t.Errorf("writeText - Expected\n%s\nBut got\n%s", expected, out.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeJSON(&out, testResults); err != nil {
+		t.Fatal(err)
+	}
+	var records []record
+	if err := json.Unmarshal(out.Bytes(), &records); err != nil {
+		t.Fatalf("writeJSON - Output isn't a JSON array: %v\n%s", err, out.String())
+	}
+	if len(records) != 2 || !reflect.DeepEqual(records[0], testResults[0].record()) {
+		t.Errorf("writeJSON - Got\n\t%+v", records)
+	}
+
+	// Nothing to say is still a valid (empty) array.
+	out.Reset()
+	if err := writeJSON(&out, nil); err != nil || strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("writeJSON - Expected [] for no results, got %q (error %v)", out.String(), err)
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeNDJSON(&out, testResults); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(testResults) {
+		t.Fatalf("writeNDJSON - Expected %d lines, got\n%s", len(testResults), out.String())
+	}
+	for i, line := range lines {
+		var r record
+		if err := json.Unmarshal([]byte(line), &r); err != nil || r.Word != string(testResults[i].whole) {
+			t.Errorf("writeNDJSON - Line %d: got %+v (error %v)", i+1, r, err)
+		}
+	}
+}
+
+func TestDelimited(t *testing.T) {
+	awkward := potentials{
+		{whole: word("a,b\"c"), components: words{word("a,b"), word("\"c")}},
+	}
+
+	var dTests = []struct {
+		sep     rune
+		join    string
+		header  bool
+		results potentials
+		expect  string
+	}{
+		{',', "+", true, testResults[:1],
+			"word,length,count,components\nquartsplat,10,2,quart+splat\n"},
+		{'\t', "+", false, testResults,
+			"quartsplat\t10\t2\tquart+splat\nnaïveté\t9\t0\t\n"},
+		{',', "+", false, awkward,
+			"\"a,b\"\"c\",5,2,\"a,b+\"\"c\"\n"},
+		{';', "+", false, awkward,
+			"\"a,b\"\"c\";5;2;\"a,b+\"\"c\"\n"},
+		{'\t', " ", false, potentials{{whole: word("c++c"), components: words{word("c++"), word("c")}}},
+			"c++c\t4\t2\tc++ c\n"},
+	}
+
+	for _, tst := range dTests {
+		var out bytes.Buffer
+		if err := newDelimited(tst.sep, tst.join, tst.header)(&out, tst.results); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != tst.expect {
+			t.Errorf("newDelimited - %q: Expected\n%q\nBut got\n%q", tst.sep, tst.expect, out.String())
+		}
+	}
+}
+
+func TestOutputOptionsFormatter(t *testing.T) {
+	var ooTests = []struct {
+		o    outputOptions
+		errs bool
+	}{
+		{outputOptions{format: "text"}, false},
+		{outputOptions{format: "json"}, false},
+		{outputOptions{format: "ndjson"}, false},
+		{outputOptions{format: "csv"}, false},
+		{outputOptions{format: "tsv", separator: "|"}, false},
+		{outputOptions{format: "csv", join: " "}, false},
+		{outputOptions{format: "csv", separator: "::"}, true},
+		{outputOptions{format: "xml"}, true},
+		{outputOptions{format: "human"}, false},
+		{outputOptions{format: "text", color: "sometimes"}, true},
+	}
+	for _, tst := range ooTests {
+		f, err := tst.o.formatter()
+		if (err != nil) != tst.errs || (err == nil && f == nil) {
+			t.Errorf("formatter - %+v: got error %v", tst.o, err)
+		}
+	}
+
+	// -sep overrides the format's own separator.
+	f, _ := outputOptions{format: "tsv", separator: "|"}.formatter()
+	var out bytes.Buffer
+	if err := f(&out, testResults[:1]); err != nil || out.String() != "quartsplat|10|2|quart+splat\n" {
+		t.Errorf("formatter - Expected a |-separated row, got %q (error %v)", out.String(), err)
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	var ucTests = []struct {
+		o      outputOptions
+		expect bool
+	}{
+		{outputOptions{color: colorAuto, terminal: true}, true},
+		{outputOptions{color: colorAuto, terminal: false}, false},
+		{outputOptions{color: colorAuto, terminal: true, noColor: true}, false},
+		{outputOptions{color: colorAlways, terminal: false, noColor: true}, true},
+		{outputOptions{color: colorNever, terminal: true}, false},
+	}
+	for _, tst := range ucTests {
+		if actual := tst.o.useColor(); actual != tst.expect {
+			t.Errorf("useColor - %+v came back %v / expected %v", tst.o, actual, tst.expect)
+		}
+	}
+}
+
+func TestNoColorSet(t *testing.T) {
+	var ncTests = []struct {
+		value  string
+		expect bool
+	}{
+		{"1", true},
+		{"false", true},
+		{"", false},
+	}
+	for _, tst := range ncTests {
+		t.Setenv("NO_COLOR", tst.value)
+		if actual := noColorSet(); actual != tst.expect {
+			t.Errorf("noColorSet - NO_COLOR=%q came back %v / expected %v", tst.value, actual, tst.expect)
+		}
+	}
+}
+
+func TestHumanChosen(t *testing.T) {
+	// Piped text must stay exactly what scripts have always parsed.
+	var out bytes.Buffer
+	f, _ := outputOptions{format: "text", color: colorAuto}.formatter()
+	_ = f(&out, testResults[:1])
+	if out.String() != "quartsplat = quart + splat\n" {
+		t.Errorf("formatter - Piped text changed: %q", out.String())
+	}
+
+	// At a terminal, it's laid out for people instead.
+	out.Reset()
+	f, _ = outputOptions{format: "text", color: colorAuto, terminal: true}.formatter()
+	_ = f(&out, testResults[:1])
+	if !strings.Contains(out.String(), componentColors[0]) {
+		t.Errorf("formatter - Expected color at a terminal, got %q", out.String())
+	}
+}
+
+func TestHuman(t *testing.T) {
+	results := potentials{
+		{whole: word("foobar"), components: words{word("foo"), word("bar")}},
+		{whole: word("quartsplat"), components: words{word("quart"), word("splat")}, score: -3.5},
+		{whole: word("naïve")},
+	}
+
+	var out bytes.Buffer
+	if err := newHuman(false)(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	expected := "foobar     = foo + bar\n" +
+		"quartsplat = quart + splat [log P = -3.50]\n" +
+		"naïve      [NOT COMPOUND]\n"
+	if out.String() != expected {
+		t.Errorf("newHuman - Expected\n%s\nBut got\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	if err := newHuman(true)(&out, results[:1]); err != nil {
+		t.Fatal(err)
+	}
+	c0, c1 := componentColors[0], componentColors[1]
+	expected = c0 + "foo" + colorReset + c1 + "bar" + colorReset + " = " +
+		c0 + "foo" + colorReset + " + " + c1 + "bar" + colorReset + "\n"
+	if out.String() != expected {
+		t.Errorf("newHuman - Expected\n%q\nBut got\n%q", expected, out.String())
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	var tTests = []struct {
+		text   string
+		expect string
+	}{
+		{`{{join .Components "|"}}`, "quart|splat\n"},
+		{`{{join .Components "-"}}`, "quart-splat\n"},
+		{`{{range $i, $c := .Components}}{{if eq $i 0}}<b>{{$c}}</b>{{else}}{{$c}}{{end}}{{end}}`,
+			"<b>quart</b>splat\n"},
+		{`{{.Word}} {{.Length}} {{.Parts}} {{.Source}}`, "quartsplat 10 2 test.list\n"},
+		{`{{upper .Word}} {{title (index .Components 1)}} {{lower "ABC"}}`, "QUARTSPLAT Splat abc\n"},
+		{`{{index .Offsets 1}}`, "5\n"},
+	}
+
+	for _, tst := range tTests {
+		f, err := newTemplate(tst.text)
+		if err != nil {
+			t.Fatalf("newTemplate - %s: %v", tst.text, err)
+		}
+		var out bytes.Buffer
+		if err = f(&out, testResults[:1]); err != nil || out.String() != tst.expect {
+			t.Errorf("newTemplate - %s: Expected %q but got %q (error %v)", tst.text, tst.expect, out.String(), err)
+		}
+	}
+
+	if _, err := newTemplate(`{{.Word`); err == nil {
+		t.Errorf("newTemplate - A malformed template should be an error")
+	}
+	f, _ := newTemplate(`{{.Nonesuch}}`)
+	if err := f(&bytes.Buffer{}, testResults[:1]); err == nil {
+		t.Errorf("newTemplate - An unknown field should be an error")
+	}
+
+	// A template wins over any format.
+	f, err := outputOptions{format: "json", template: `{{.Word}}`}.formatter()
+	var out bytes.Buffer
+	if err != nil || f(&out, testResults[:1]) != nil || out.String() != "quartsplat\n" {
+		t.Errorf("formatter - Expected the template to be used, got %q (error %v)", out.String(), err)
+	}
+}
+
+func TestTitle(t *testing.T) {
+	for in, expected := range map[string]string{"foo": "Foo", "élan": "Élan", "": "", "Bar": "Bar"} {
+		if actual := title(in); actual != expected {
+			t.Errorf("title - %q: Expected %q but got %q", in, expected, actual)
+		}
+	}
+}
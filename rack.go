@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// blank is what stands for a blank tile in a rack: it can be played as
+// any letter at all.
+const blank = '?'
+
+// A rack is the letters a word may be made out of, as in a word game:
+// how many of each there are, and how many blanks.
+type rack struct {
+	letters [256]int
+	blanks  int
+}
+
+func newRack(tiles string) (r rack) {
+	for i := 0; i < len(tiles); i++ {
+		if tiles[i] == blank {
+			r.blanks++
+		} else {
+			r.letters[tiles[i]]++
+		}
+	}
+	return
+}
+
+// take uses up a tile for b, a blank if there's no b left, and reports
+// whether there was either.  give puts back what take took.
+func (r *rack) take(b byte) (usedBlank, ok bool) {
+	switch {
+	case r.letters[b] > 0:
+		r.letters[b]--
+		return false, true
+	case r.blanks > 0:
+		r.blanks--
+		return true, true
+	}
+	return false, false
+}
+
+func (r *rack) give(b byte, usedBlank bool) {
+	if usedBlank {
+		r.blanks++
+	} else {
+		r.letters[b]++
+	}
+}
+
+// playable returns every word on d's graph which can be made out of the
+// tiles on r, and every run of up to parts components which can be,
+// longest first, and in order within a length.
+//
+// The graph is walked depth first, and a branch is only followed while
+// there's a tile left for it, so the walk never strays further than the
+// rack allows.  Where a component ends, the walk can also start over
+// from the top of the graph, to find the next component of a compound.
+// The number of runs grows very quickly with the size of the rack and
+// the number of short words on the list, hence the limit on parts.
+func (r rack) playable(d dictionary, parts int) (found words) {
+	seen := make(map[string]bool)
+	var walk func(g bytegraph, w word, start, part int)
+	walk = func(g bytegraph, w word, start, part int) {
+		if g.endOfWord && len(w) > start {
+			last := d.isComponent(w[start:])
+			if (start == 0 || last) && !seen[string(w)] {
+				seen[string(w)] = true
+				found = append(found, append(word(nil), w...))
+			}
+			if part < parts && last {
+				walk(d.graph, w, len(w), part+1)
+			}
+		}
+		for b, next := range g.next {
+			usedBlank, ok := r.take(b)
+			if !ok {
+				continue
+			}
+			walk(next, append(w, b), start, part)
+			r.give(b, usedBlank)
+		}
+	}
+	walk(d.graph, nil, 0, 1)
+
+	sort.Slice(found, func(i, j int) bool {
+		if len(found[i]) != len(found[j]) {
+			return len(found[i]) > len(found[j])
+		}
+		return string(found[i]) < string(found[j])
+	})
+	return
+}
+
+// rackFlags are the flags of the rack command, which takes the tiles on
+// the rack, and then the word lists to play them with.
+func rackFlags(fs *flag.FlagSet) action {
+	parts := fs.Int("parts", 2, "most `words` to make a run of (1 for single words only)")
+	var df dictionaryFlags
+	df.register(fs)
+	var outOpts outputOptions
+	outOpts.register(fs)
+
+	return func(fs *flag.FlagSet) int {
+		if fs.NArg() == 0 {
+			return misuse(fs, errors.New("no rack given"))
+		}
+		if fs.NArg() == 1 {
+			return misuse(fs, errors.New("no word lists given"))
+		}
+		if *parts < 1 {
+			return misuse(fs, fmt.Errorf("bad -parts %d", *parts))
+		}
+		if err := df.finish(); err != nil {
+			return misuse(fs, err)
+		}
+		format, err := outOpts.formatterFor(os.Stdout)
+		if err != nil {
+			return misuse(fs, fmt.Errorf("bad output settings: %v", err))
+		}
+
+		dict, ws, err := loadDictionary(fs.Args()[1:], df)
+		if err != nil {
+			return cantLoad(fs, err)
+		}
+		results, _ := checkWords(newRack(fs.Arg(0)).playable(dict, *parts), dict)
+		for i := range results {
+			results[i].sources = ws.sources(results[i].whole)
+		}
+		if err = format(os.Stdout, results); err != nil {
+			complain(err)
+			return exitIO
+		}
+		if len(results) == 0 {
+			fmt.Fprintln(os.Stderr, "No words can be made from the rack.")
+			return exitNoCompound
+		}
+		return exitSuccess
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRackTakeGive(t *testing.T) {
+	r := newRack("ab?")
+	if r.letters['a'] != 1 || r.letters['b'] != 1 || r.blanks != 1 {
+		t.Fatalf("newRack - Got %d a, %d b, %d blanks", r.letters['a'], r.letters['b'], r.blanks)
+	}
+	if usedBlank, ok := r.take('a'); usedBlank || !ok {
+		t.Errorf("rack.take - Expected an a, got blank %v, ok %v", usedBlank, ok)
+	}
+	if usedBlank, ok := r.take('a'); !usedBlank || !ok {
+		t.Errorf("rack.take - Expected a blank, got blank %v, ok %v", usedBlank, ok)
+	}
+	if _, ok := r.take('a'); ok {
+		t.Errorf("rack.take - Expected nothing left for a")
+	}
+	r.give('a', true)
+	if r.blanks != 1 || r.letters['a'] != 0 {
+		t.Errorf("rack.give - Got %d a, %d blanks", r.letters['a'], r.blanks)
+	}
+}
+
+func TestRackPlayable(t *testing.T) {
+	var rTests = []struct {
+		tiles    string
+		parts    int
+		expected words
+	}{
+		{"foobar", 1, words{word("foobar"), word("bar"), word("foo")}},
+		{"foobar", 2, words{word("barfoo"), word("foobar"), word("bar"), word("foo")}},
+		{"qu?rt", 1, words{word("quart"), word("art"), word("qu")}},
+		{"qu?rt", 2, words{word("artqu"), word("quart"), word("art"), word("qu")}},
+		{"xyz", 2, nil},
+	}
+	for _, rt := range rTests {
+		if actual := newRack(rt.tiles).playable(testDict, rt.parts); !reflect.DeepEqual(actual, rt.expected) {
+			t.Errorf("rack.playable - For %s and %d parts, expected %q, got %q", rt.tiles, rt.parts, rt.expected, actual)
+		}
+	}
+
+	stop := graphOf(words{word("art")}, nil)
+	d := testDict
+	d.stop = &stop
+	expected := words{word("quart"), word("art"), word("qu")}
+	if actual := newRack("qu?rt").playable(d, 2); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("rack.playable - With art stopped, expected %q, got %q", expected, actual)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// maxRequest is the most a request body may hold, in bytes.
+const maxRequest = 1 << 20
+
+// shutdownGrace is how long requests in flight get to finish once the
+// server has been asked to stop.
+const shutdownGrace = 10 * time.Second
+
+// A server answers questions about a dictionary over HTTP.  Nothing in
+// it changes once it's been built, so any number of requests can share
+// it at once without locking.
+type server struct {
+	dict       dictionary
+	ws         *wordset
+	candidates map[int]potentials
+}
+
+// newServer gets the dictionary ready to serve, finding its candidates up
+// front so that each /longest request only has to check them.
+func newServer(d dictionary, ws *wordset) *server {
+	list := make(words, len(ws.list))
+	copy(list, ws.list)
+	sort.Sort(list)
+	return &server{dict: d, ws: ws, candidates: findCandidates(list, d.graph)}
+}
+
+func (sv *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/decompose", only(http.MethodPost, sv.decompose))
+	mux.HandleFunc("/check", only(http.MethodGet, sv.check))
+	mux.HandleFunc("/longest", only(http.MethodGet, sv.longest))
+	return mux
+}
+
+// only turns away any request to h which doesn't use the given method.
+func only(method string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			replyError(w, http.StatusMethodNotAllowed, fmt.Errorf("use %s", method))
+			return
+		}
+		h(w, r)
+	}
+}
+
+// records turns results into what the JSON output formats write.
+func (sv *server) records(results potentials) []record {
+	rs := make([]record, 0, len(results))
+	for _, p := range results {
+		p.sources = sv.ws.sources(p.whole)
+		rs = append(rs, p.record())
+	}
+	return rs
+}
+
+// decompose answers a POST of {"words": ["backyard", ...]} with a record
+// for each of the words, in the same order.
+func (sv *server) decompose(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Words []string `json:"words"`
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequest))
+	if err := dec.Decode(&req); err != nil {
+		replyError(w, http.StatusBadRequest, fmt.Errorf("bad request body: %v", err))
+		return
+	}
+	if len(req.Words) == 0 {
+		replyError(w, http.StatusBadRequest, errors.New("no words given"))
+		return
+	}
+
+	queries := make(words, 0, len(req.Words))
+	for _, q := range req.Words {
+		queries = append(queries, word(q))
+	}
+	results, _ := checkWords(queries, sv.dict)
+	reply(w, http.StatusOK, sv.records(results))
+}
+
+// check answers GET /check?word=backyard with the record for that word.
+func (sv *server) check(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("word")
+	if q == "" {
+		replyError(w, http.StatusBadRequest, errors.New("no word given"))
+		return
+	}
+	results, _ := checkWords(words{word(q)}, sv.dict)
+	reply(w, http.StatusOK, sv.records(results)[0])
+}
+
+// longest answers GET /longest?n=10 with the records for the n longest
+// compound words in the dictionary, longest first.  n defaults to 1.
+func (sv *server) longest(w http.ResponseWriter, r *http.Request) {
+	n := 1
+	if arg := r.URL.Query().Get("n"); arg != "" {
+		var err error
+		if n, err = strconv.Atoi(arg); err != nil || n < 1 {
+			replyError(w, http.StatusBadRequest, fmt.Errorf("bad n %q", arg))
+			return
+		}
+	}
+	reply(w, http.StatusOK, sv.records(longestCompounds(sv.candidates, sv.dict, n)))
+}
+
+func reply(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func replyError(w http.ResponseWriter, status int, err error) {
+	reply(w, status, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+// serveFlags are the flags of the serve command, which loads the
+// dictionary once, then answers requests about it until it's sent SIGTERM
+// or interrupted.
+func serveFlags(fs *flag.FlagSet) action {
+	var dictFiles fileList
+	fs.Var(&dictFiles, "d", "word `file` to serve")
+	addr := fs.String("addr", ":8080", "`host:port` to listen on")
+	var df dictionaryFlags
+	df.register(fs)
+
+	return func(fs *flag.FlagSet) int {
+		if len(dictFiles) == 0 {
+			return misuse(fs, errors.New("no dictionary given with -d"))
+		}
+		if fs.NArg() > 0 {
+			return misuse(fs, fmt.Errorf("unexpected %q", fs.Arg(0)))
+		}
+		if err := df.finish(); err != nil {
+			return misuse(fs, err)
+		}
+
+		dict, ws, err := loadDictionary(dictFiles, df)
+		if err != nil {
+			return cantLoad(fs, err)
+		}
+		// Listening first means a port that's taken is reported as such,
+		// rather than after claiming to be serving on it.
+		ln, err := net.Listen("tcp", *addr)
+		if err != nil {
+			complain(err)
+			return exitIO
+		}
+		srv := &http.Server{Addr: *addr, Handler: newServer(dict, ws).routes()}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+		served := make(chan error, 1)
+		go func() { served <- srv.Serve(ln) }()
+		fmt.Fprintf(os.Stderr, "Serving %d words on %s.\n", len(ws.list), ln.Addr())
+
+		select {
+		case err = <-served:
+			complain(err)
+			return exitIO
+		case <-ctx.Done():
+		}
+
+		fmt.Fprintln(os.Stderr, "Shutting down.")
+		grace, cancel := context.WithTimeout(context.Background(), shutdownGrace)
+		defer cancel()
+		if err = srv.Shutdown(grace); err != nil {
+			complain(err)
+			return exitIO
+		}
+		return exitSuccess
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testServer() *server {
+	ws := newWordset()
+	for i, w := range testWords {
+		ws.add(w, 0, occurrence{"test.list", i + 1})
+	}
+	return newServer(dictionary{graph: graphOf(ws.list, nil), minLen: 2}, ws)
+}
+
+// serveTest makes a request of sv, and decodes whatever JSON comes back
+// into v.
+func serveTest(t *testing.T, sv *server, method, target, body string, v interface{}) int {
+	rec := httptest.NewRecorder()
+	sv.routes().ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s %s - Content-Type is %q", method, target, ct)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Errorf("%s %s - Bad JSON %q: %v", method, target, rec.Body.String(), err)
+	}
+	return rec.Code
+}
+
+func TestServeDecompose(t *testing.T) {
+	sv := testServer()
+	var rs []record
+	code := serveTest(t, sv, "POST", "/decompose", `{"words": ["quartsplat", "fibble"]}`, &rs)
+	if code != http.StatusOK || len(rs) != 2 {
+		t.Fatalf("POST /decompose - Got %d %+v", code, rs)
+	}
+	if !rs[0].Compound || len(rs[0].Components) != 2 || rs[0].Components[1].Offset != 5 || rs[1].Compound {
+		t.Errorf("POST /decompose - Got %+v", rs)
+	}
+
+	for _, body := range []string{`{"words": []}`, `not json`} {
+		var e struct{ Error string }
+		if code = serveTest(t, sv, "POST", "/decompose", body, &e); code != http.StatusBadRequest || e.Error == "" {
+			t.Errorf("POST /decompose - %s: Got %d %+v", body, code, e)
+		}
+	}
+}
+
+func TestServeCheck(t *testing.T) {
+	sv := testServer()
+	var r record
+	code := serveTest(t, sv, "GET", "/check?word=foobar", "", &r)
+	if code != http.StatusOK || !r.Compound || r.Word != "foobar" || len(r.Sources) != 1 {
+		t.Errorf("GET /check - Got %d %+v", code, r)
+	}
+
+	var e struct{ Error string }
+	if code = serveTest(t, sv, "GET", "/check", "", &e); code != http.StatusBadRequest {
+		t.Errorf("GET /check - Expected a bad request without a word, got %d", code)
+	}
+	if code = serveTest(t, sv, "POST", "/check?word=foobar", "", &e); code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /check - Expected method not allowed, got %d", code)
+	}
+}
+
+func TestServeLongest(t *testing.T) {
+	sv := testServer()
+	var ltTests = []struct {
+		target string
+		code   int
+		expect []string
+	}{
+		{"/longest", http.StatusOK, []string{"barfooquux"}},
+		{"/longest?n=2", http.StatusOK, []string{"barfooquux", "foobar"}},
+		{"/longest?n=0", http.StatusBadRequest, nil},
+		{"/longest?n=lots", http.StatusBadRequest, nil},
+	}
+
+	for _, tst := range ltTests {
+		var rs []record
+		var e struct{ Error string }
+		var v interface{} = &rs
+		if tst.code != http.StatusOK {
+			v = &e
+		}
+		if code := serveTest(t, sv, "GET", tst.target, "", v); code != tst.code {
+			t.Errorf("GET %s - Expected %d, got %d", tst.target, tst.code, code)
+		}
+		var actual []string
+		for _, r := range rs {
+			actual = append(actual, r.Word)
+		}
+		if strings.Join(actual, " ") != strings.Join(tst.expect, " ") {
+			t.Errorf("GET %s - Expected %q, got %q", tst.target, tst.expect, actual)
+		}
+	}
+}
+
+func TestServeAddrInUse(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	list := filepath.Join(t.TempDir(), "test.list")
+	if err = os.WriteFile(list, []byte("foo\nbar\nfoobar\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var status int
+	_, stderr := capture(t, func() { status = run([]string{"serve", "-d", list, "-addr", ln.Addr().String()}) })
+	if status != exitIO || strings.Contains(stderr, "Serving") {
+		t.Errorf("serve - Expected to fail without claiming to serve, got status %d\n%s", status, stderr)
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A session is an interactive shell over a dictionary which is loaded
+// once, and which can then be questioned, and changed, as often as you
+// like.  Every line typed is kept in history, and also written to hist
+// if that's set, so that it's there for the next session too.
+type session struct {
+	dict    dictionary
+	ws      *wordset
+	format  formatter
+	out     io.Writer
+	errs    io.Writer
+	history []string
+	hist    io.Writer
+}
+
+// A shellCommand is one of the things that can be asked of a session.
+// args describes the arguments it takes, for help and for complaining
+// about the wrong ones.
+type shellCommand struct {
+	args string
+	help string
+	run  func(s *session, args []string) error
+}
+
+// errQuit is what a command returns to end the session.
+var errQuit = errors.New("quit")
+
+var shellCommands = map[string]shellCommand{
+	"split":    {"word [word ...]", "shows how each word splits into components", (*session).split},
+	"check":    {"word [word ...]", "says whether each word is compound", (*session).check},
+	"prefix":   {"word [word ...]", "lists the words on the list that each word begins with", (*session).prefix},
+	"explain":  {"word [word ...]", "shows why each word is or isn't compound", (*session).explain},
+	"stats":    {"", "reports facts about the word list and its graph", (*session).stats},
+	"add":      {"word [count]", "puts a word on the list, with an optional count", (*session).add},
+	"remove":   {"word", "takes a word off the list", (*session).remove},
+	"strategy": {"[greedy|probable|fewest]", "shows or changes how splits are chosen", (*session).setStrategy},
+	"history":  {"", "lists the lines typed so far", (*session).listHistory},
+	"quit":     {"", "ends the session, as does end of input", func(*session, []string) error { return errQuit }},
+}
+
+// help can't be in the table above, since it reads the table.
+func init() {
+	shellCommands["help"] = shellCommand{"", "lists these commands", (*session).help}
+}
+
+// run reads lines from in until it runs out, doing what each one says.
+// The prompt, if any, is written before each line is read.
+func (s *session) run(in io.Reader, prompt string) error {
+	r := bufio.NewReader(in)
+	for {
+		fmt.Fprint(s.out, prompt)
+		line, err := r.ReadString('\n')
+		if line = strings.TrimSpace(line); line != "" {
+			if s.do(line) == errQuit {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			// Leave the terminal on a fresh line after ^D.
+			if prompt != "" {
+				fmt.Fprintln(s.out)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// do remembers line, and carries out the command on it.  Anything that
+// goes wrong is reported, and the session carries on; errQuit is passed
+// back to say that it shouldn't.
+func (s *session) do(line string) error {
+	s.remember(line)
+	fields := strings.Fields(line)
+	cmd, known := shellCommands[fields[0]]
+	if !known {
+		fmt.Fprintf(s.errs, "Unknown command %q; try \"help\".\n", fields[0])
+		return nil
+	}
+	err := cmd.run(s, fields[1:])
+	if err != nil && err != errQuit {
+		fmt.Fprintf(s.errs, "%s: %v\n", fields[0], err)
+	}
+	return err
+}
+
+func (s *session) remember(line string) {
+	s.history = append(s.history, line)
+	if s.hist != nil {
+		fmt.Fprintln(s.hist, line)
+	}
+}
+
+// needWords complains unless it's been given at least one word.
+func needWords(args []string) error {
+	if len(args) == 0 {
+		return errors.New("which word?")
+	}
+	return nil
+}
+
+func (s *session) split(args []string) error {
+	if err := needWords(args); err != nil {
+		return err
+	}
+	queries := make(words, 0, len(args))
+	for _, a := range args {
+		queries = append(queries, word(a))
+	}
+	results, _ := checkWords(queries, s.dict)
+	for i := range results {
+		results[i].sources = s.ws.sources(results[i].whole)
+	}
+	return s.format(s.out, results)
+}
+
+func (s *session) check(args []string) error {
+	if err := needWords(args); err != nil {
+		return err
+	}
+	for _, a := range args {
+		p := potential{whole: word(a), prefixes: prefixesOf(word(a), s.dict.graph)}
+		answer := "not compound"
+		if (&p).isCompound(s.dict) {
+			answer = "compound"
+		}
+		if isWord(p.whole, s.dict.graph) {
+			answer += ", and on the list"
+		}
+		fmt.Fprintf(s.out, "%s: %s\n", a, answer)
+	}
+	return nil
+}
+
+func (s *session) prefix(args []string) error {
+	if err := needWords(args); err != nil {
+		return err
+	}
+	for _, a := range args {
+		pfxs := prefixesOf(word(a), s.dict.graph)
+		if len(pfxs) == 0 {
+			fmt.Fprintf(s.out, "%s: no word on the list is a prefix of it\n", a)
+			continue
+		}
+		names := make([]string, 0, len(pfxs))
+		for _, pfx := range pfxs {
+			names = append(names, string(pfx))
+		}
+		fmt.Fprintf(s.out, "%s: %s\n", a, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+func (s *session) explain(args []string) error {
+	if err := needWords(args); err != nil {
+		return err
+	}
+	for _, a := range args {
+		if _, err := explain(s.out, word(a), s.dict); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *session) stats(args []string) error {
+	pm := findCandidates(s.ws.list, s.dict.graph)
+	return writeStats(s.out, gatherStats(s.ws.list, pm, s.dict), nil)
+}
+
+func (s *session) add(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: add word [count]")
+	}
+	w, count := word(args[0]), 0
+	if len(args) == 2 {
+		var err error
+		if count, err = strconv.Atoi(args[1]); err != nil || count < 0 {
+			return fmt.Errorf("bad count %q", args[1])
+		}
+	}
+
+	// A word which is already on the list has the count added to its
+	// weight, so the total goes up by however much the weight did.
+	before := s.ws.weights[string(w)]
+	isNew := s.ws.add(w, count, occurrence{file: "<shell>", line: len(s.history)})
+	after := s.ws.weights[string(w)]
+	_ = makegraph(w, after, &s.dict.graph)
+	s.dict.total += after - before
+	if len(w) < s.dict.minLen {
+		s.dict.minLen = len(w)
+	}
+	if isNew {
+		fmt.Fprintf(s.out, "Added %s.\n", w)
+	} else {
+		fmt.Fprintf(s.out, "%s was already on the list.\n", w)
+	}
+	return nil
+}
+
+func (s *session) remove(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: remove word")
+	}
+	w := word(args[0])
+	s.dict.total -= s.ws.weights[string(w)]
+	if !s.ws.remove(w) {
+		return fmt.Errorf("%s isn't on the list", w)
+	}
+	_ = unmakegraph(w, &s.dict.graph)
+	fmt.Fprintf(s.out, "Removed %s.\n", w)
+
+	// Taking away the last count leaves nothing to be probable with.
+	if s.dict.checkStrategy() != nil {
+		s.dict.strategy = ""
+		fmt.Fprintf(s.out, "No counts are left, so the strategy is now %s.\n", s.dict.chosenStrategy())
+	}
+	return nil
+}
+
+func (s *session) setStrategy(args []string) error {
+	switch len(args) {
+	case 0:
+		fmt.Fprintf(s.out, "The strategy is %s.\n", s.dict.chosenStrategy())
+		return nil
+	case 1:
+		was := s.dict.strategy
+		s.dict.strategy = args[0]
+		if err := s.dict.checkStrategy(); err != nil {
+			s.dict.strategy = was
+			return err
+		}
+		fmt.Fprintf(s.out, "The strategy is now %s.\n", s.dict.chosenStrategy())
+		return nil
+	}
+	return errors.New("usage: strategy [greedy|probable|fewest]")
+}
+
+func (s *session) listHistory(args []string) error {
+	for i, line := range s.history {
+		fmt.Fprintf(s.out, "%5d  %s\n", i+1, line)
+	}
+	return nil
+}
+
+func (s *session) help(args []string) error {
+	var names []string
+	for name := range shellCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cmd := shellCommands[name]
+		fmt.Fprintf(s.out, "%-35s %s\n", strings.TrimSpace(name+" "+cmd.args), cmd.help)
+	}
+	return nil
+}
+
+// readHistory returns the lines in the history file, if there is one.
+func readHistory(file string) (lines []string, err error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hs := bufio.NewScanner(f)
+	for hs.Scan() {
+		if line := strings.TrimSpace(hs.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, hs.Err()
+}
+
+// shellFlags are the flags of the shell command, which loads the
+// dictionary once, then takes commands from STDIN until it runs out of
+// them.
+func shellFlags(fs *flag.FlagSet) action {
+	var dictFiles fileList
+	fs.Var(&dictFiles, "d", "word `file` to load")
+	historyFile := fs.String("history", ".compound_history", "`file` to keep command history in")
+	var df dictionaryFlags
+	df.register(fs)
+	var outOpts outputOptions
+	outOpts.register(fs)
+
+	return func(fs *flag.FlagSet) int {
+		if len(dictFiles) == 0 {
+			return misuse(fs, errors.New("no dictionary given with -d"))
+		}
+		if fs.NArg() > 0 {
+			return misuse(fs, fmt.Errorf("unexpected %q", fs.Arg(0)))
+		}
+		if err := df.finish(); err != nil {
+			return misuse(fs, err)
+		}
+		format, err := outOpts.formatterFor(os.Stdout)
+		if err != nil {
+			return misuse(fs, fmt.Errorf("bad output settings: %v", err))
+		}
+
+		s := &session{format: format, out: os.Stdout, errs: os.Stderr}
+		if s.dict, s.ws, err = loadDictionary(dictFiles, df); err != nil {
+			return cantLoad(fs, err)
+		}
+		if *historyFile != "" {
+			if s.history, err = readHistory(*historyFile); err != nil {
+				complain(err)
+				return exitIO
+			}
+			hist, err := os.OpenFile(*historyFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+			if err != nil {
+				complain(err)
+				return exitIO
+			}
+			defer hist.Close()
+			s.hist = hist
+		}
+
+		prompt := ""
+		if isTerminal(os.Stdin) {
+			prompt = "compound> "
+			fmt.Fprintf(os.Stderr, "Loaded %d words.  Type \"help\" for the commands.\n", len(s.ws.list))
+		}
+		if err = s.run(os.Stdin, prompt); err != nil {
+			complain(err)
+			return exitIO
+		}
+		return exitSuccess
+	}
+}
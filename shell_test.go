@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testSession is a session over a dictionary of its own, since sessions
+// change their dictionaries, with everything it writes going to out.
+func testSession(out *bytes.Buffer) *session {
+	ws := newWordset()
+	for i, w := range testWords {
+		ws.add(w, 0, occurrence{"test.list", i + 1})
+	}
+	return &session{
+		dict:   dictionary{graph: graphOf(ws.list, nil), minLen: 2},
+		ws:     ws,
+		format: writeText,
+		out:    out,
+		errs:   out,
+	}
+}
+
+func TestSessionRun(t *testing.T) {
+	var sTests = []struct {
+		script  string
+		mention []string
+	}{
+		{"split quartsplat fibble\n", []string{"quartsplat = quart + splat", "fibble [NOT COMPOUND]"}},
+		{"check foobar quartsplat fibble\n", []string{
+			"foobar: compound, and on the list", "quartsplat: compound\n", "fibble: not compound\n",
+		}},
+		{"prefix quartful bogus\n", []string{"quartful: quart, qu", "bogus: no word on the list"}},
+		{"explain quartsplat\n", []string{"Result: quartsplat = quart + splat"}},
+		{"stats\n", []string{"Words:", "Graph nodes:"}},
+		{"add ful\nsplit splatful\nremove ful\nsplit splatful\n", []string{
+			"Added ful.", "splatful = splat + ful", "Removed ful.", "splatful [NOT COMPOUND]",
+		}},
+		{"add foo\nremove ful\nadd\n", []string{
+			"foo was already on the list.", "remove: ful isn't on the list", "add: usage: add word [count]",
+		}},
+		{"strategy\nstrategy fewest\nstrategy probable\nstrategy\n", []string{
+			"The strategy is greedy.", "The strategy is now fewest.",
+			"strategy: the probable strategy needs", "The strategy is fewest.",
+		}},
+		{"bogus\nsplit\n", []string{`Unknown command "bogus"`, "split: which word?"}},
+		{"help\n", []string{"add word [count]", "strategy [greedy|probable|fewest]"}},
+		{"history\n\n  \nhistory", []string{"    1  history\n    2  history\n"}},
+	}
+
+	for _, tst := range sTests {
+		var out bytes.Buffer
+		if err := testSession(&out).run(strings.NewReader(tst.script), ""); err != nil {
+			t.Errorf("session.run - %q: %v", tst.script, err)
+		}
+		for _, m := range tst.mention {
+			if !strings.Contains(out.String(), m) {
+				t.Errorf("session.run - %q: missing %q from\n%s", tst.script, m, out.String())
+			}
+		}
+	}
+}
+
+func TestSessionQuit(t *testing.T) {
+	var out, hist bytes.Buffer
+	s := testSession(&out)
+	s.hist = &hist
+
+	if err := s.run(strings.NewReader("split foobar\nquit\nsplit quartsplat\n"), "> "); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "quartsplat") {
+		t.Errorf("session.run - Carried on after quit:\n%s", out.String())
+	}
+	if hist.String() != "split foobar\nquit\n" {
+		t.Errorf("session.run - Expected both lines in the history file, got %q", hist.String())
+	}
+}
+
+func TestSessionAddWeighted(t *testing.T) {
+	var out bytes.Buffer
+	s := testSession(&out)
+	_ = s.do("add ful 10")
+	_ = s.do("add f")
+	if s.dict.total != 10 || s.dict.minLen != 1 || s.ws.weights["ful"] != 10 {
+		t.Errorf("session.add - Got total %d, minLen %d, weights %v", s.dict.total, s.dict.minLen, s.ws.weights)
+	}
+
+	// Adding a word that's already there adds to its weight, on the list,
+	// on the graph and in the total alike.
+	_ = s.do("add ful 5")
+	weight := 0
+	s.dict.graph.visitFrom(word("ful"), func(w word, wt int) bool {
+		if string(w) == "ful" {
+			weight = wt
+		}
+		return true
+	})
+	if s.dict.total != 15 || s.ws.weights["ful"] != 15 || weight != 15 || s.dict.total != s.ws.weights.total() {
+		t.Errorf("session.add - Got total %d, weights %v, graph weight %d", s.dict.total, s.ws.weights, weight)
+	}
+
+	_ = s.do("strategy probable")
+	_ = s.do("remove ful")
+	if s.dict.total != 0 || s.dict.chosenStrategy() != strategyGreedy {
+		t.Errorf("session.remove - Got total %d, strategy %s\n%s", s.dict.total, s.dict.chosenStrategy(), out.String())
+	}
+}
+
+func TestReadHistory(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "history")
+
+	if lines, err := readHistory(file); lines != nil || err != nil {
+		t.Errorf("readHistory - Expected nothing from a missing file, got %q (error %v)", lines, err)
+	}
+	if err := os.WriteFile(file, []byte("split foo\n\nstats\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := readHistory(file)
+	if err != nil || !reflect.DeepEqual(lines, []string{"split foo", "stats"}) {
+		t.Errorf("readHistory - Got %q (error %v)", lines, err)
+	}
+}
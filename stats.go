@@ -0,0 +1,172 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// A stopwatch keeps track of how long each phase of a run takes.  Each
+// lap is the time since the one before, and laps with the same name are
+// added together.
+type stopwatch struct {
+	phases []string
+	took   map[string]time.Duration
+	last   time.Time
+}
+
+func newStopwatch() *stopwatch {
+	return &stopwatch{took: make(map[string]time.Duration), last: time.Now()}
+}
+
+func (sw *stopwatch) lap(phase string) {
+	now := time.Now()
+	if _, seen := sw.took[phase]; !seen {
+		sw.phases = append(sw.phases, phase)
+	}
+	sw.took[phase] += now.Sub(sw.last)
+	sw.last = now
+}
+
+// wordStats are the facts about a word list and its graph which are
+// useful for comparing one vocabulary with another.  All the maps are
+// indexed by word length, except compoundsByParts, which is indexed by
+// the number of components.  candidates counts every word which begins
+// with another, and pruned how many of those were passed over for not
+// ending with one.
+type wordStats struct {
+	words            int
+	byLength         map[int]int
+	candidates       map[int]int
+	pruned           map[int]int
+	compounds        map[int]int
+	compoundsByParts map[int]int
+	nodes            int
+	innerNodes       int
+	edges            int
+}
+
+// gatherStats looks at every word on list, and checks every candidate in
+// pm to see whether it really is compound, passing over those which don't
+// end with a word, as the usual run does.  Unlike the usual run, this
+// doesn't stop at the first compound word it finds.
+func gatherStats(list words, pm map[int]potentials, d dictionary) (st wordStats) {
+	st.words = len(list)
+	st.byLength = make(map[int]int)
+	st.candidates = make(map[int]int)
+	st.pruned = make(map[int]int)
+	st.compounds = make(map[int]int)
+	st.compoundsByParts = make(map[int]int)
+
+	for _, w := range list {
+		st.byLength[len(w)]++
+	}
+	for l, ps := range pm {
+		st.candidates[l] = len(ps)
+		for _, p := range ps {
+			if !endsInWord(p.whole, d.graph) {
+				st.pruned[l]++
+			} else if (&p).isCompound(d) {
+				st.compounds[l]++
+				st.compoundsByParts[len(p.components)]++
+			}
+		}
+	}
+	st.nodes, st.innerNodes, st.edges = countNodes(d.graph)
+	return
+}
+
+// countNodes counts the nodes of g, how many of them lead anywhere, and
+// how many edges there are between them.
+func countNodes(g bytegraph) (nodes, inner, edges int) {
+	nodes = 1
+	if len(g.next) > 0 {
+		inner = 1
+	}
+	for _, next := range g.next {
+		n, i, e := countNodes(next)
+		nodes, inner, edges = nodes+n, inner+i, edges+e+1
+	}
+	return
+}
+
+// branching is the average number of ways onward from a node which has
+// any at all.
+func (st wordStats) branching() float64 {
+	if st.innerNodes == 0 {
+		return 0
+	}
+	return float64(st.edges) / float64(st.innerNodes)
+}
+
+// writeStats lays out st, and the time each phase took, as tables.  A nil
+// sw leaves the times out.
+func writeStats(out io.Writer, st wordStats, sw *stopwatch) error {
+	tw := tabwriter.NewWriter(out, 0, 8, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintf(tw, "Words:\t%d\t\n", st.words)
+	fmt.Fprintf(tw, "Graph nodes:\t%d\t\n", st.nodes)
+	fmt.Fprintf(tw, "Average branching factor:\t%.3f\t\n", st.branching())
+	fmt.Fprintln(tw)
+
+	var lengths []int
+	for l := range st.byLength {
+		lengths = append(lengths, l)
+	}
+	for l := range st.candidates {
+		if _, counted := st.byLength[l]; !counted {
+			lengths = append(lengths, l)
+		}
+	}
+	sort.Ints(lengths)
+	fmt.Fprintln(tw, "Length\tWords\tCandidates\tPruned\tCompounds\t")
+	for _, l := range lengths {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t\n", l, st.byLength[l], st.candidates[l], st.pruned[l], st.compounds[l])
+	}
+	fmt.Fprintln(tw)
+
+	var parts []int
+	for n := range st.compoundsByParts {
+		parts = append(parts, n)
+	}
+	sort.Ints(parts)
+	fmt.Fprintln(tw, "Components\tCompounds\t")
+	for _, n := range parts {
+		fmt.Fprintf(tw, "%d\t%d\t\n", n, st.compoundsByParts[n])
+	}
+
+	if sw != nil {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "Phase\tTime\t")
+		for _, phase := range sw.phases {
+			fmt.Fprintf(tw, "%s\t%v\t\n", phase, sw.took[phase].Round(time.Microsecond))
+		}
+	}
+
+	return tw.Flush()
+}
+
+// statsFlags are the flags of the stats command, which checks every word,
+// and reports on the lists rather than on the words.
+func statsFlags(fs *flag.FlagSet) action {
+	var sf searchFlags
+	sf.register(fs)
+
+	return func(fs *flag.FlagSet) int {
+		s, status := setUpSearch(fs, sf, fs.Args())
+		if s == nil {
+			return status
+		}
+		st := gatherStats(s.candidates.list, s.byLength, s.dict)
+		s.sw.lap("search")
+		if err := writeStats(os.Stdout, st, s.sw); err != nil {
+			complain(err)
+			return exitIO
+		}
+		return exitSuccess
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStopwatch(t *testing.T) {
+	sw := newStopwatch()
+	sw.lap("load")
+	sw.lap("sort")
+	time.Sleep(time.Millisecond)
+	sw.lap("load")
+
+	if !reflect.DeepEqual(sw.phases, []string{"load", "sort"}) {
+		t.Errorf("stopwatch - Expected phases [load sort], got %q", sw.phases)
+	}
+	if sw.took["load"] < time.Millisecond {
+		t.Errorf("stopwatch - Laps with the same name should add up; load took %v", sw.took["load"])
+	}
+}
+
+func TestCountNodes(t *testing.T) {
+	// root, a, ab, abc, abcd, z, za; only abcd and za lead nowhere.
+	nodes, inner, edges := countNodes(shortGraph)
+	if nodes != 7 || inner != 5 || edges != 6 {
+		t.Errorf("countNodes - Expected 7 nodes, 5 inner, 6 edges; got %d, %d, %d", nodes, inner, edges)
+	}
+}
+
+func TestGatherStats(t *testing.T) {
+	_, pm := graphAndFindCandidates(shortWords, nil)
+	d := dictionary{graph: shortGraph, minLen: 1}
+	st := gatherStats(shortWords, pm, d)
+
+	if st.words != 5 {
+		t.Errorf("gatherStats - Expected 5 words, got %d", st.words)
+	}
+	if expected := map[int]int{1: 2, 2: 2, 4: 1}; !reflect.DeepEqual(expected, st.byLength) {
+		t.Errorf("gatherStats - Expected lengths %v, got %v", expected, st.byLength)
+	}
+	// Candidates are counted before any are passed over; "ab" and "abcd"
+	// don't end with a word, so they're counted as pruned as well.
+	if expected := map[int]int{2: 2, 4: 1}; !reflect.DeepEqual(expected, st.candidates) {
+		t.Errorf("gatherStats - Expected candidates %v, got %v", expected, st.candidates)
+	}
+	if expected := map[int]int{2: 1, 4: 1}; !reflect.DeepEqual(expected, st.pruned) {
+		t.Errorf("gatherStats - Expected pruned %v, got %v", expected, st.pruned)
+	}
+	// "za" = z + a, but there's no "b" for "ab", nor "cd" or "bcd" for
+	// "abcd".
+	if expected := map[int]int{2: 1}; !reflect.DeepEqual(expected, st.compounds) {
+		t.Errorf("gatherStats - Expected compounds %v, got %v", expected, st.compounds)
+	}
+	if expected := map[int]int{2: 1}; !reflect.DeepEqual(expected, st.compoundsByParts) {
+		t.Errorf("gatherStats - Expected compounds by parts %v, got %v", expected, st.compoundsByParts)
+	}
+	if st.branching() != 1.2 {
+		t.Errorf("gatherStats - Expected a branching factor of 1.2, got %v", st.branching())
+	}
+}
+
+func TestWriteStats(t *testing.T) {
+	_, pm := graphAndFindCandidates(shortWords, nil)
+	st := gatherStats(shortWords, pm, dictionary{graph: shortGraph, minLen: 1})
+	sw := newStopwatch()
+	sw.lap("load")
+	sw.lap("search")
+
+	var out bytes.Buffer
+	if err := writeStats(&out, st, sw); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"Words:", "Graph nodes:", "Average branching factor:", "1.200",
+		"Length", "Candidates", "Pruned", "Components", "load", "search"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("writeStats - Missing %q from\n%s", expected, out.String())
+		}
+	}
+
+	// Without a stopwatch, there are no times to report.
+	out.Reset()
+	if err := writeStats(&out, st, nil); err != nil || strings.Contains(out.String(), "Phase") {
+		t.Errorf("writeStats - Expected no phases without a stopwatch, got\n%s (error %v)", out.String(), err)
+	}
+}